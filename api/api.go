@@ -0,0 +1,364 @@
+// Package api implements the Harbormaster routes independently of the
+// transport, so the same handlers answer API Gateway events and plain HTTP
+// requests with identical JSON bodies.
+package api
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/provider"
+	"github.com/buzzsurfr/harbormaster/snapshot"
+)
+
+// Request is a transport-neutral API request
+type Request struct {
+	Method                string
+	Path                  string
+	PathParameters        map[string]string
+	QueryStringParameters map[string]string
+	Headers               map[string]string
+}
+
+// Response is a transport-neutral API response. Body is encoded as JSON.
+// Headers are added to the headers sent on every response.
+type Response struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       interface{}
+}
+
+// HandlerFunc answers a single API request
+type HandlerFunc func(ctx context.Context, req Request) Response
+
+// Route binds a handler to a method and resource path. Resource paths use the
+// API Gateway template syntax, e.g. /clusters/{scheduler}/{name}.
+type Route struct {
+	Method   string
+	Resource string
+	Handler  HandlerFunc
+}
+
+// API serves the inventory gathered by a set of providers
+type API struct {
+	providers provider.Set
+	history   snapshot.History
+}
+
+// New returns an API backed by the given providers
+func New(providers provider.Set) *API {
+	return &API{providers: providers}
+}
+
+// WithHistory serves point-in-time queries and the history of resources from
+// the snapshots kept by h
+func (a *API) WithHistory(h snapshot.History) *API {
+	a.history = h
+	return a
+}
+
+// List is the envelope returned by list routes. Errors name each scheduler
+// or cluster that couldn't be read, so missing items can be told apart from
+// items that don't exist.
+type List struct {
+	Items  interface{}       `json:"items"`
+	Errors []*provider.Error `json:"errors"`
+}
+
+// ClusterDetail is the body of the cluster detail route. Errors name the
+// counts that couldn't be taken, in which case the cluster is returned
+// without them.
+type ClusterDetail struct {
+	cluster.Cluster
+	Errors []*provider.Error `json:"errors,omitempty"`
+}
+
+// Routes returns every route served by the API
+func (a *API) Routes() []Route {
+	return []Route{
+		{Method: "GET", Resource: "/clusters", Handler: a.ListClusters},
+		{Method: "GET", Resource: "/clusters/{scheduler}/{name}", Handler: a.DescribeCluster},
+		{Method: "GET", Resource: "/nodes", Handler: a.ListNodes},
+		{Method: "GET", Resource: "/nodes/{scheduler}/{cluster}/{name}", Handler: a.DescribeNode},
+		{Method: "GET", Resource: "/services", Handler: a.ListServices},
+		{Method: "GET", Resource: "/services/{scheduler}/{cluster}/{namespace}/{name}", Handler: a.DescribeService},
+		{Method: "GET", Resource: "/tasks", Handler: a.ListTasks},
+		{Method: "GET", Resource: "/tasks/{scheduler}/{cluster}/{id}", Handler: a.DescribeTask},
+		{Method: "GET", Resource: "/tasks/{scheduler}/{cluster}/{namespace}/{name}", Handler: a.DescribeTask},
+		{Method: "GET", Resource: "/containers", Handler: a.ListContainers},
+		{Method: "GET", Resource: "/workloads", Handler: a.ListWorkloads},
+		{Method: "GET", Resource: "/history/{resource}", Handler: a.History},
+		{Method: "GET", Resource: "/diff", Handler: a.Diff},
+	}
+}
+
+// ListClusters handles GET /clusters
+func (a *API) ListClusters(ctx context.Context, req Request) Response {
+	ctx, freshness := discovery(ctx, req)
+	providers, resp, ok := a.providersAt(ctx, req)
+	if !ok {
+		return resp
+	}
+
+	// List clusters from all providers and count the ones whose scheduler
+	// doesn't report counts
+	clusters, errs := providers.ListClusters(ctx)
+	clusters, countErrs := providers.CountClusters(ctx, clusters)
+	errs = append(errs, countErrs...)
+
+	return withAge(Response{StatusCode: 200, Body: List{Items: clusters, Errors: errs}}, freshness)
+}
+
+// DescribeCluster handles GET /clusters/{scheduler}/{name}
+func (a *API) DescribeCluster(ctx context.Context, req Request) Response {
+	ctx, freshness := discovery(ctx, req)
+	providers, resp, ok := a.providersAt(ctx, req)
+	if !ok {
+		return resp
+	}
+
+	// Determine which provider to use based on scheduler
+	currentScheduler := req.PathParameters["scheduler"]
+	currentName := req.PathParameters["name"]
+
+	p, ok := providers.Get(currentScheduler)
+	if !ok {
+		return badRequest("unknown scheduler %q", currentScheduler)
+	}
+
+	currentCluster, err := p.DescribeCluster(ctx, currentName)
+	if err != nil {
+		return failure(err)
+	}
+
+	// A cluster that can't be counted is returned without counts and the
+	// error is reported, as in ListClusters
+	counted, errs := provider.Set{p}.CountClusters(ctx, []cluster.Cluster{currentCluster})
+	return withAge(Response{StatusCode: 200, Body: ClusterDetail{Cluster: counted[0], Errors: errs}}, freshness)
+}
+
+// ListNodes handles GET /nodes
+func (a *API) ListNodes(ctx context.Context, req Request) Response {
+	ctx, freshness := discovery(ctx, req)
+	providers, resp, ok := a.providersAt(ctx, req)
+	if !ok {
+		return resp
+	}
+
+	// List nodes of every cluster from all providers
+	nodes, errs := providers.ListNodes(ctx)
+
+	return withAge(Response{StatusCode: 200, Body: List{Items: nodes, Errors: errs}}, freshness)
+}
+
+// DescribeNode handles GET /nodes/{scheduler}/{cluster}/{name}
+func (a *API) DescribeNode(ctx context.Context, req Request) Response {
+	ctx, freshness := discovery(ctx, req)
+	providers, resp, ok := a.providersAt(ctx, req)
+	if !ok {
+		return resp
+	}
+
+	// Determine which provider to use based on scheduler
+	currentScheduler := req.PathParameters["scheduler"]
+	currentClusterName := req.PathParameters["cluster"]
+	currentName := req.PathParameters["name"]
+
+	p, ok := providers.Get(currentScheduler)
+	if !ok {
+		return badRequest("unknown scheduler %q", currentScheduler)
+	}
+
+	currentCluster, err := p.DescribeCluster(ctx, currentClusterName)
+	if err != nil {
+		return failure(err)
+	}
+
+	currentNode, err := p.DescribeNode(ctx, currentCluster, currentName)
+	if err != nil {
+		return failure(err)
+	}
+
+	return withAge(Response{StatusCode: 200, Body: currentNode}, freshness)
+}
+
+// ListServices handles GET /services. ?fields=name,status,desiredCount keeps
+// only the listed fields of each service.
+func (a *API) ListServices(ctx context.Context, req Request) Response {
+	ctx, freshness := discovery(ctx, req)
+	providers, resp, ok := a.providersAt(ctx, req)
+	if !ok {
+		return resp
+	}
+
+	// List services of every cluster from all providers
+	services, errs := providers.ListServices(ctx)
+
+	if fields, ok := req.QueryStringParameters["fields"]; ok {
+		projected, err := project(services, fields)
+		if err != nil {
+			return badRequest("%v", err)
+		}
+		return withAge(Response{StatusCode: 200, Body: List{Items: projected, Errors: errs}}, freshness)
+	}
+
+	return withAge(Response{StatusCode: 200, Body: List{Items: services, Errors: errs}}, freshness)
+}
+
+// DescribeService handles GET /services/{scheduler}/{cluster}/{namespace}/{name}.
+// ECS services have no namespace, so any namespace, e.g. "-", finds them.
+func (a *API) DescribeService(ctx context.Context, req Request) Response {
+	ctx, freshness := discovery(ctx, req)
+	providers, resp, ok := a.providersAt(ctx, req)
+	if !ok {
+		return resp
+	}
+
+	// Determine which provider to use based on scheduler
+	currentScheduler := req.PathParameters["scheduler"]
+	currentClusterName := req.PathParameters["cluster"]
+	currentNamespace := req.PathParameters["namespace"]
+	currentName := req.PathParameters["name"]
+
+	p, ok := providers.Get(currentScheduler)
+	if !ok {
+		return badRequest("unknown scheduler %q", currentScheduler)
+	}
+
+	currentCluster, err := p.DescribeCluster(ctx, currentClusterName)
+	if err != nil {
+		return failure(err)
+	}
+
+	currentService, err := p.DescribeService(ctx, currentCluster, currentNamespace, currentName)
+	if err != nil {
+		return failure(err)
+	}
+
+	return withAge(Response{StatusCode: 200, Body: currentService}, freshness)
+}
+
+// discovery returns the context for the provider calls of a request. Cached
+// results are bypassed when the caller asks for fresh data with ?refresh=true
+// or Cache-Control: no-cache.
+func discovery(ctx context.Context, req Request) (context.Context, *provider.Freshness) {
+	if req.QueryStringParameters["refresh"] == "true" || strings.Contains(header(req, "Cache-Control"), "no-cache") {
+		ctx = provider.WithRefresh(ctx)
+	}
+	return provider.WithFreshness(ctx)
+}
+
+// withAge sets the Age header of a response to the age in seconds of the
+// oldest result it contains
+func withAge(resp Response, freshness *provider.Freshness) Response {
+	age := time.Duration(0)
+	if discovered := freshness.Discovered(); !discovered.IsZero() {
+		age = time.Since(discovered)
+	}
+
+	if resp.Headers == nil {
+		resp.Headers = map[string]string{}
+	}
+	resp.Headers["Age"] = strconv.Itoa(int(age / time.Second))
+	return resp
+}
+
+// header returns a request header regardless of the case it was sent in
+func header(req Request, name string) string {
+	for k, v := range req.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// ListTasks handles GET /tasks
+func (a *API) ListTasks(ctx context.Context, req Request) Response {
+	ctx, freshness := discovery(ctx, req)
+	providers, resp, ok := a.providersAt(ctx, req)
+	if !ok {
+		return resp
+	}
+
+	// List tasks and pods of every cluster from all providers
+	tasks, errs := providers.ListTasks(ctx)
+
+	return withAge(Response{StatusCode: 200, Body: List{Items: tasks, Errors: errs}}, freshness)
+}
+
+// ListWorkloads handles GET /workloads
+func (a *API) ListWorkloads(ctx context.Context, req Request) Response {
+	ctx, freshness := discovery(ctx, req)
+	providers, resp, ok := a.providersAt(ctx, req)
+	if !ok {
+		return resp
+	}
+
+	// List ECS services and Kubernetes workload controllers of every
+	// cluster from all providers
+	workloads, errs := providers.ListWorkloads(ctx)
+
+	return withAge(Response{StatusCode: 200, Body: List{Items: workloads, Errors: errs}}, freshness)
+}
+
+// DescribeTask handles GET /tasks/{scheduler}/{cluster}/{id} and, for pods
+// whose ID is namespace/name, GET /tasks/{scheduler}/{cluster}/{namespace}/{name}
+func (a *API) DescribeTask(ctx context.Context, req Request) Response {
+	ctx, freshness := discovery(ctx, req)
+	providers, resp, ok := a.providersAt(ctx, req)
+	if !ok {
+		return resp
+	}
+
+	// Determine which provider to use based on scheduler
+	currentScheduler := req.PathParameters["scheduler"]
+	currentClusterName := req.PathParameters["cluster"]
+	currentID := req.PathParameters["id"]
+	if namespace, ok := req.PathParameters["namespace"]; ok {
+		currentID = namespace + "/" + req.PathParameters["name"]
+	}
+
+	p, ok := providers.Get(currentScheduler)
+	if !ok {
+		return badRequest("unknown scheduler %q", currentScheduler)
+	}
+
+	currentCluster, err := p.DescribeCluster(ctx, currentClusterName)
+	if err != nil {
+		return failure(err)
+	}
+
+	currentTask, err := p.DescribeTask(ctx, currentCluster, currentID)
+	if err != nil {
+		return failure(err)
+	}
+
+	return withAge(Response{StatusCode: 200, Body: currentTask}, freshness)
+}
+
+// providersAt returns the providers that answer a request. Requests with
+// ?at=<RFC3339> are answered from the snapshot in effect at that time.
+func (a *API) providersAt(ctx context.Context, req Request) (provider.Set, Response, bool) {
+	if _, ok := req.QueryStringParameters["at"]; !ok {
+		return a.providers, Response{}, true
+	}
+
+	s, resp, ok := a.snapshotAt(ctx, req, "at")
+	if !ok {
+		return nil, resp, false
+	}
+	return snapshot.Providers(s, a.schedulers()...), Response{}, true
+}
+
+// schedulers returns the scheduler of every provider
+func (a *API) schedulers() []string {
+	schedulers := make([]string, len(a.providers))
+	for i, p := range a.providers {
+		schedulers[i] = p.Scheduler()
+	}
+	return schedulers
+}
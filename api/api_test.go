@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/provider"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/buzzsurfr/harbormaster/snapshot"
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/buzzsurfr/harbormaster/upstream"
+	"github.com/buzzsurfr/harbormaster/workload"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubProvider serves a fixed list of clusters
+type stubProvider struct {
+	clusters  []cluster.Cluster
+	listCalls int
+}
+
+func (p *stubProvider) Scheduler() string {
+	return "ecs"
+}
+
+func (p *stubProvider) ListClusters(ctx context.Context) ([]cluster.Cluster, error) {
+	p.listCalls++
+	return p.clusters, nil
+}
+
+func (p *stubProvider) DescribeCluster(ctx context.Context, name string) (cluster.Cluster, error) {
+	for _, c := range p.clusters {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return cluster.Cluster{}, &provider.Error{
+		Scheduler: "ecs",
+		Cluster:   name,
+		Operation: "ecs:DescribeClusters",
+		Kind:      upstream.NotFound,
+		Code:      provider.CodeNotFound,
+		Message:   "cluster " + name + " not found",
+		Err:       provider.ErrNotFound,
+	}
+}
+
+func (p *stubProvider) ListNodes(ctx context.Context, c cluster.Cluster) ([]node.Node, error) {
+	return []node.Node{}, nil
+}
+
+func (p *stubProvider) DescribeNode(ctx context.Context, c cluster.Cluster, name string) (node.Node, error) {
+	return node.Node{}, nil
+}
+
+func (p *stubProvider) ListServices(ctx context.Context, c cluster.Cluster) ([]service.Service, error) {
+	return []service.Service{}, nil
+}
+
+func (p *stubProvider) DescribeService(ctx context.Context, c cluster.Cluster, namespace, name string) (service.Detail, error) {
+	return service.Detail{}, nil
+}
+
+func (p *stubProvider) ListTasks(ctx context.Context, c cluster.Cluster) ([]task.Task, error) {
+	return []task.Task{}, nil
+}
+
+func (p *stubProvider) DescribeTask(ctx context.Context, c cluster.Cluster, id string) (task.Task, error) {
+	return task.Task{ID: id}, nil
+}
+
+func (p *stubProvider) ListWorkloads(ctx context.Context, c cluster.Cluster) ([]workload.Workload, error) {
+	return []workload.Workload{}, nil
+}
+
+func newTestAPI() *API {
+	return New(provider.Set{&stubProvider{clusters: []cluster.Cluster{
+		{Name: "default", Arn: "arn:aws:ecs:us-east-1:123456789012:cluster/default", Scheduler: "ecs", Status: "ACTIVE"},
+	}}})
+}
+
+func TestMatchResource(t *testing.T) {
+	params, ok := matchResource("/nodes/{scheduler}/{cluster}/{name}", "/nodes/ecs/default/abc123")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"scheduler": "ecs", "cluster": "default", "name": "abc123"}, params)
+
+	_, ok = matchResource("/clusters/{scheduler}/{name}", "/clusters/ecs")
+	assert.False(t, ok)
+
+	_, ok = matchResource("/clusters", "/nodes")
+	assert.False(t, ok)
+}
+
+func TestServeHTTP(t *testing.T) {
+	a := newTestAPI()
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/clusters/ecs/default", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"name":"default","arn":"arn:aws:ecs:us-east-1:123456789012:cluster/default","scheduler":"ecs","status":"ACTIVE"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/clusters", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"items":[{"name":"default","arn":"arn:aws:ecs:us-east-1:123456789012:cluster/default","scheduler":"ecs","status":"ACTIVE"}],"errors":[]}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/tasks", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"items":[],"errors":[]}`, rec.Body.String())
+
+	// Pods are described by namespace and name
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/tasks/ecs/default/web/web-1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"id":"web/web-1"`)
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/deployments", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("POST", "/clusters", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.JSONEq(t, `{"message":"Method Not Allowed"}`, rec.Body.String())
+}
+
+func TestServeHTTPCache(t *testing.T) {
+	stub := &stubProvider{clusters: []cluster.Cluster{{Name: "default", Scheduler: "ecs"}}}
+	a := New(provider.Set{stub}.Cached(provider.DefaultCacheTTL))
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/clusters", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("Age"))
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/clusters", nil))
+	assert.Equal(t, 1, stub.listCalls)
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/clusters?refresh=true", nil))
+	assert.Equal(t, 2, stub.listCalls)
+
+	req := httptest.NewRequest("GET", "/clusters", nil)
+	req.Header.Set("Cache-Control", "no-cache")
+	a.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, 3, stub.listCalls)
+}
+
+func TestServeHTTPErrors(t *testing.T) {
+	a := newTestAPI()
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/clusters/nomad/default", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"message":"unknown scheduler \"nomad\"","kind":"InvalidInput"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/clusters/ecs/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"message":"cluster missing not found","kind":"NotFound","code":"NotFound","scheduler":"ecs","cluster":"missing","operation":"ecs:DescribeClusters"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/nodes/ecs/missing/abc123", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// uncountableProvider counts clusters but can't reach any of them
+type uncountableProvider struct {
+	stubProvider
+}
+
+func (p *uncountableProvider) CountCluster(ctx context.Context, c cluster.Cluster) (cluster.Cluster, error) {
+	return c, &provider.Error{Scheduler: "ecs", Cluster: c.Name, Operation: "kubernetes:Connect", Kind: upstream.Failure, Message: "no route to host"}
+}
+
+func TestDescribeClusterWithoutCounts(t *testing.T) {
+	a := New(provider.Set{&uncountableProvider{stubProvider{clusters: []cluster.Cluster{
+		{Name: "default", Arn: "arn:aws:ecs:us-east-1:123456789012:cluster/default", Scheduler: "ecs", Status: "ACTIVE"},
+	}}}})
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/clusters/ecs/default", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"name": "default",
+		"arn": "arn:aws:ecs:us-east-1:123456789012:cluster/default",
+		"scheduler": "ecs",
+		"status": "ACTIVE",
+		"errors": [{"scheduler": "ecs", "cluster": "default", "operation": "kubernetes:Connect", "kind": "Failure", "message": "no route to host"}]
+	}`, rec.Body.String())
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, http.StatusNotFound, statusCode(&provider.Error{Kind: upstream.NotFound}))
+	assert.Equal(t, http.StatusForbidden, statusCode(&provider.Error{Kind: upstream.AccessDenied}))
+	assert.Equal(t, http.StatusBadRequest, statusCode(&provider.Error{Kind: upstream.InvalidInput}))
+	assert.Equal(t, http.StatusServiceUnavailable, statusCode(&provider.Error{Kind: upstream.Throttled}))
+	assert.Equal(t, http.StatusGatewayTimeout, statusCode(&provider.Error{Kind: upstream.Timeout}))
+	assert.Equal(t, http.StatusBadGateway, statusCode(&provider.Error{Kind: upstream.Failure}))
+
+	// Throttled calls can be retried once the upstream limit recovers
+	throttled := failure(&provider.Error{Kind: upstream.Throttled})
+	assert.Equal(t, http.StatusServiceUnavailable, throttled.StatusCode)
+	assert.Equal(t, "5", throttled.Headers["Retry-After"])
+	assert.Nil(t, failure(&provider.Error{Kind: upstream.Timeout}).Headers)
+
+	// Nothing can be served from snapshots until the first one is collected
+	assert.Equal(t, http.StatusServiceUnavailable, failure(snapshot.ErrNoSnapshot).StatusCode)
+	assert.Equal(t, http.StatusServiceUnavailable, statusCode(&provider.Error{Kind: upstream.Failure, Err: snapshot.ErrNoSnapshot}))
+	assert.Equal(t, http.StatusNotImplemented, statusCode(&provider.Error{Kind: upstream.InvalidInput, Err: snapshot.ErrNotStored}))
+}
+
+func TestLambdaRouter(t *testing.T) {
+	router := LambdaRouter(newTestAPI().Routes())
+
+	// Declared resource
+	resp, err := router(context.Background(), events.APIGatewayProxyRequest{
+		Resource:       "/clusters/{scheduler}/{name}",
+		Path:           "/clusters/ecs/default",
+		HTTPMethod:     "GET",
+		PathParameters: map[string]string{"scheduler": "ecs", "name": "default"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Body, `"name":"default"`)
+
+	// Greedy proxy resource
+	resp, err = router(context.Background(), events.APIGatewayProxyRequest{
+		Resource:   "/{proxy+}",
+		Path:       "/clusters/ecs/default",
+		HTTPMethod: "GET",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Body, `"name":"default"`)
+
+	resp, err = router(context.Background(), events.APIGatewayProxyRequest{
+		Resource:   "/{proxy+}",
+		Path:       "/unknown",
+		HTTPMethod: "GET",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/buzzsurfr/harbormaster/task"
+)
+
+// ListContainers handles GET /containers. It searches the containers of every
+// task and pod, keeping those whose image contains ?image=, whose image
+// digest is ?digest= and whose name, status and health match ?name=,
+// ?status= and ?health=. Status and health are matched regardless of case.
+func (a *API) ListContainers(ctx context.Context, req Request) Response {
+	ctx, freshness := discovery(ctx, req)
+	providers, resp, ok := a.providersAt(ctx, req)
+	if !ok {
+		return resp
+	}
+
+	// List tasks and pods of every cluster from all providers
+	tasks, errs := providers.ListTasks(ctx)
+
+	matches := []task.Match{}
+	for _, t := range tasks {
+		for _, c := range t.Containers {
+			if !matchContainer(c, req.QueryStringParameters) {
+				continue
+			}
+			matches = append(matches, task.Match{
+				Container: c,
+				Task:      t.ID,
+				Scheduler: t.Scheduler,
+				Cluster:   t.Cluster,
+				Namespace: t.Namespace,
+				Node:      t.Node,
+			})
+		}
+	}
+
+	return withAge(Response{StatusCode: 200, Body: List{Items: matches, Errors: errs}}, freshness)
+}
+
+// matchContainer reports whether a container satisfies every search parameter
+func matchContainer(c task.Container, params map[string]string) bool {
+	if name, ok := params["name"]; ok && c.Name != name {
+		return false
+	}
+	if image, ok := params["image"]; ok && !strings.Contains(c.Image, image) {
+		return false
+	}
+	if digest, ok := params["digest"]; ok && c.ImageDigest != digest {
+		return false
+	}
+	if status, ok := params["status"]; ok && !strings.EqualFold(c.Status, status) {
+		return false
+	}
+	if health, ok := params["health"]; ok && !strings.EqualFold(c.Health, health) {
+		return false
+	}
+	return true
+}
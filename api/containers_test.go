@@ -0,0 +1,25 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchContainer(t *testing.T) {
+	c := task.Container{
+		Name:        "app",
+		Image:       "123456789012.dkr.ecr.us-east-1.amazonaws.com/web:1.4.2",
+		ImageDigest: "sha256:3f9a",
+		Status:      "RUNNING",
+		Health:      "HEALTHY",
+	}
+
+	assert.True(t, matchContainer(c, nil))
+	assert.True(t, matchContainer(c, map[string]string{"image": "web:1.4", "status": "running"}))
+	assert.True(t, matchContainer(c, map[string]string{"name": "app", "digest": "sha256:3f9a", "health": "healthy"}))
+	assert.False(t, matchContainer(c, map[string]string{"image": "web:1.5"}))
+	assert.False(t, matchContainer(c, map[string]string{"name": "sidecar"}))
+	assert.False(t, matchContainer(c, map[string]string{"health": "unhealthy"}))
+}
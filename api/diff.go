@@ -0,0 +1,29 @@
+package api
+
+import (
+	"context"
+
+	"github.com/buzzsurfr/harbormaster/snapshot"
+)
+
+// Diff handles GET /diff?from=<RFC3339>&to=<RFC3339>. It compares the
+// snapshots in effect at each time; to defaults to the latest snapshot.
+func (a *API) Diff(ctx context.Context, req Request) Response {
+	if a.history == nil {
+		return notImplemented("diffs need a snapshot history")
+	}
+	if _, ok := req.QueryStringParameters["from"]; !ok {
+		return badRequest("from is required")
+	}
+
+	from, resp, ok := a.snapshotAt(ctx, req, "from")
+	if !ok {
+		return resp
+	}
+	to, resp, ok := a.snapshotAt(ctx, req, "to")
+	if !ok {
+		return resp
+	}
+
+	return Response{StatusCode: 200, Body: snapshot.Compare(from, to)}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/provider"
+	"github.com/buzzsurfr/harbormaster/snapshot"
+	"github.com/buzzsurfr/harbormaster/upstream"
+)
+
+// Error is the JSON body of every failed response
+type Error struct {
+	Message   string        `json:"message"`
+	Kind      upstream.Kind `json:"kind,omitempty"`
+	Code      string        `json:"code,omitempty"`
+	Scheduler string        `json:"scheduler,omitempty"`
+	Cluster   string        `json:"cluster,omitempty"`
+	Operation string        `json:"operation,omitempty"`
+}
+
+// errorResponse returns a response carrying the standard text for a status
+func errorResponse(status int) Response {
+	return Response{
+		StatusCode: status,
+		Body:       Error{Message: http.StatusText(status)},
+	}
+}
+
+// badRequest rejects invalid input from the caller
+func badRequest(format string, args ...interface{}) Response {
+	return Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       Error{Message: fmt.Sprintf(format, args...), Kind: upstream.InvalidInput},
+	}
+}
+
+// notFound reports a resource that doesn't exist
+func notFound(format string, args ...interface{}) Response {
+	return Response{
+		StatusCode: http.StatusNotFound,
+		Body:       Error{Message: fmt.Sprintf(format, args...), Kind: upstream.NotFound},
+	}
+}
+
+// notImplemented rejects routes the API isn't configured to serve
+func notImplemented(format string, args ...interface{}) Response {
+	return Response{
+		StatusCode: http.StatusNotImplemented,
+		Body:       Error{Message: fmt.Sprintf(format, args...)},
+	}
+}
+
+// throttledRetryAfter is how long callers are asked to wait before retrying
+// a request that failed because the upstream call was throttled
+const throttledRetryAfter = 5 * time.Second
+
+// failure maps an error from a provider to a response
+func failure(err error) Response {
+	e, ok := err.(*provider.Error)
+	if !ok {
+		e = &provider.Error{Kind: upstream.Classify(err), Message: err.Error(), Err: err}
+	}
+
+	var responseHeaders map[string]string
+	if e.Kind == upstream.Throttled {
+		responseHeaders = map[string]string{"Retry-After": strconv.Itoa(int(throttledRetryAfter / time.Second))}
+	}
+
+	return Response{
+		StatusCode: statusCode(e),
+		Headers:    responseHeaders,
+		Body: Error{
+			Message:   e.Message,
+			Kind:      e.Kind,
+			Code:      e.Code,
+			Scheduler: e.Scheduler,
+			Cluster:   e.Cluster,
+			Operation: e.Operation,
+		},
+	}
+}
+
+// statusCode chooses the HTTP status for a failed provider call. Failures
+// that aren't the caller's fault are reported as upstream errors, except for
+// details that snapshots don't keep, while the first snapshot to answer from
+// is being collected and while AWS or Kubernetes throttles calls, which the
+// caller can retry later. Throttling is never reported as 429 because the
+// caller didn't exceed any limit.
+func statusCode(e *provider.Error) int {
+	switch e.Err {
+	case snapshot.ErrNoSnapshot:
+		return http.StatusServiceUnavailable
+	case snapshot.ErrNotStored:
+		return http.StatusNotImplemented
+	}
+
+	switch e.Kind {
+	case upstream.NotFound:
+		return http.StatusNotFound
+	case upstream.AccessDenied:
+		return http.StatusForbidden
+	case upstream.InvalidInput:
+		return http.StatusBadRequest
+	case upstream.Timeout:
+		return http.StatusGatewayTimeout
+	case upstream.Throttled:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// project keeps only the named top-level JSON fields of each item of a list.
+// It fails on names that aren't fields of the items, so that typos don't
+// silently return empty objects.
+func project(items interface{}, fields string) ([]map[string]interface{}, error) {
+	known := jsonFields(reflect.TypeOf(items).Elem())
+
+	names := []string{}
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		names = append(names, name)
+	}
+
+	var decoded []map[string]interface{}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	projected := make([]map[string]interface{}, len(decoded))
+	for i, item := range decoded {
+		projected[i] = map[string]interface{}{}
+		for _, name := range names {
+			if value, ok := item[name]; ok {
+				projected[i][name] = value
+			}
+		}
+	}
+	return projected, nil
+}
+
+// jsonFields returns the JSON names of the fields of a struct, including
+// those of embedded structs
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		switch {
+		case name == "-":
+		case field.Anonymous && name == "":
+			for embedded := range jsonFields(field.Type) {
+				fields[embedded] = true
+			}
+		case name == "":
+			fields[field.Name] = true
+		default:
+			fields[name] = true
+		}
+	}
+	return fields
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProject(t *testing.T) {
+	services := []service.Service{{
+		Name:         "web",
+		Status:       "ACTIVE",
+		Cluster:      cluster.Cluster{Name: "default"},
+		DesiredCount: 2,
+		Ports:        []service.Port{{Protocol: "TCP", Port: 80, TargetPort: "8080"}},
+	}}
+
+	projected, err := project(services, "name, desiredCount,ports")
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]interface{}{{
+		"name":         "web",
+		"desiredCount": float64(2),
+		"ports":        []interface{}{map[string]interface{}{"protocol": "TCP", "port": float64(80), "targetPort": "8080"}},
+	}}, projected)
+
+	// Empty omitempty fields are left out rather than reported unknown
+	projected, err = project(services, "name,type")
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]interface{}{{"name": "web"}}, projected)
+
+	_, err = project(services, "name,replicas")
+	assert.EqualError(t, err, `unknown field "replicas"`)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/provider"
+	"github.com/buzzsurfr/harbormaster/snapshot"
+)
+
+// History handles GET /history/{resource}?id=<id>[&from=<RFC3339>][&to=<RFC3339>]
+// where resource is clusters, nodes, services, tasks or workloads. It returns a
+// revision for the first snapshot in the range and for every snapshot in
+// which the resource changed.
+func (a *API) History(ctx context.Context, req Request) Response {
+	resource := req.PathParameters["resource"]
+	id := req.QueryStringParameters["id"]
+
+	if a.history == nil {
+		return notImplemented("history needs a snapshot history")
+	}
+	if _, ok := (&snapshot.Snapshot{}).Index(resource); !ok {
+		return notFound("unknown resource %q", resource)
+	}
+	if id == "" {
+		return badRequest("id is required")
+	}
+
+	from, resp, ok := timeParameter(req, "from", time.Time{})
+	if !ok {
+		return resp
+	}
+	to, resp, ok := timeParameter(req, "to", time.Now())
+	if !ok {
+		return resp
+	}
+
+	// Snapshots are read one at a time and only the revisions are kept
+	revisions := []snapshot.Revision{}
+	err := a.history.Range(ctx, from, to, func(s *snapshot.Snapshot) error {
+		revisions = snapshot.AppendRevision(revisions, s, resource, id)
+		return nil
+	})
+	if err != nil {
+		return failure(err)
+	}
+
+	return Response{StatusCode: 200, Body: List{Items: revisions, Errors: []*provider.Error{}}}
+}
+
+// snapshotAt returns the snapshot in effect at the time given by a query
+// parameter, or the latest snapshot when the parameter is missing
+func (a *API) snapshotAt(ctx context.Context, req Request, name string) (*snapshot.Snapshot, Response, bool) {
+	if a.history == nil {
+		return nil, notImplemented("point-in-time queries need a snapshot history"), false
+	}
+
+	v, ok := req.QueryStringParameters[name]
+	if !ok {
+		s, err := a.history.Latest(ctx)
+		if err == snapshot.ErrNoSnapshot {
+			return nil, notFound("no snapshot has been taken"), false
+		}
+		if err != nil {
+			return nil, failure(err), false
+		}
+		return s, Response{}, true
+	}
+
+	t, resp, ok := timeParameter(req, name, time.Time{})
+	if !ok {
+		return nil, resp, false
+	}
+
+	s, err := a.history.At(ctx, t)
+	if err == snapshot.ErrNoSnapshot {
+		return nil, notFound("no snapshot was taken at or before %s", v), false
+	}
+	if err != nil {
+		return nil, failure(err), false
+	}
+	return s, Response{}, true
+}
+
+// timeParameter parses an optional RFC 3339 query parameter
+func timeParameter(req Request, name string, fallback time.Time) (time.Time, Response, bool) {
+	v, ok := req.QueryStringParameters[name]
+	if !ok {
+		return fallback, Response{}, true
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, badRequest("%s must be an RFC 3339 time: %s", name, v), false
+	}
+	return t, Response{}, true
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/snapshot"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubHistory holds snapshots in the order they were taken
+type stubHistory struct {
+	snapshots []*snapshot.Snapshot
+}
+
+func (h *stubHistory) Put(ctx context.Context, s *snapshot.Snapshot) error {
+	h.snapshots = append(h.snapshots, s)
+	return nil
+}
+
+func (h *stubHistory) Latest(ctx context.Context) (*snapshot.Snapshot, error) {
+	return h.At(ctx, time.Now())
+}
+
+func (h *stubHistory) At(ctx context.Context, t time.Time) (*snapshot.Snapshot, error) {
+	for i := len(h.snapshots) - 1; i >= 0; i-- {
+		if !h.snapshots[i].Time.After(t) {
+			return h.snapshots[i], nil
+		}
+	}
+	return nil, snapshot.ErrNoSnapshot
+}
+
+func (h *stubHistory) Range(ctx context.Context, from, to time.Time, fn func(*snapshot.Snapshot) error) error {
+	for _, s := range h.snapshots {
+		if !s.Time.Before(from) && !s.Time.After(to) {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func newTestHistory() *stubHistory {
+	first := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	return &stubHistory{snapshots: []*snapshot.Snapshot{
+		{Time: first, Clusters: []cluster.Cluster{{Name: "default", Scheduler: "ecs", Status: "ACTIVE"}}},
+		{Time: first.Add(time.Hour), Clusters: []cluster.Cluster{{Name: "default", Scheduler: "ecs", Status: "INACTIVE"}}},
+	}}
+}
+
+func TestListAt(t *testing.T) {
+	a := newTestAPI().WithHistory(newTestHistory())
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/clusters?at=2018-10-01T12:30:00Z", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"items":[{"name":"default","arn":"","scheduler":"ecs","status":"ACTIVE"}],"errors":[]}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/clusters?at=2018-10-01T11:00:00Z", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/clusters?at=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Without a history only the latest inventory is served
+	rec = httptest.NewRecorder()
+	newTestAPI().ServeHTTP(rec, httptest.NewRequest("GET", "/clusters?at=2018-10-01T12:30:00Z", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestHistory(t *testing.T) {
+	a := newTestAPI().WithHistory(newTestHistory())
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/history/clusters?id=ecs/default", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"items":[
+		{"time":"2018-10-01T12:00:00Z","item":{"name":"default","arn":"","scheduler":"ecs","status":"ACTIVE"}},
+		{"time":"2018-10-01T13:00:00Z","item":{"name":"default","arn":"","scheduler":"ecs","status":"INACTIVE"}}
+	],"errors":[]}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/history/deployments?id=ecs/default", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	newTestAPI().ServeHTTP(rec, httptest.NewRequest("GET", "/history/clusters?id=ecs/default", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestDiff(t *testing.T) {
+	a := newTestAPI().WithHistory(newTestHistory())
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/diff?from=2018-10-01T12:00:00Z&to=2018-10-01T13:00:00Z", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"from":"2018-10-01T12:00:00Z",
+		"to":"2018-10-01T13:00:00Z",
+		"clusters":{"added":[],"removed":[],"changed":[
+			{"id":"ecs/default","fields":[{"field":"status","from":"ACTIVE","to":"INACTIVE"}]}
+		]},
+		"nodes":{"added":[],"removed":[],"changed":[]},
+		"services":{"added":[],"removed":[],"changed":[]},
+		"tasks":{"added":[],"removed":[],"changed":[]},
+		"workloads":{"added":[],"removed":[],"changed":[]}
+	}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", "/diff", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	newTestAPI().ServeHTTP(rec, httptest.NewRequest("GET", "/diff?from=2018-10-01T12:00:00Z", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	rec = httptest.NewRecorder()
+	newTestAPI().ServeHTTP(rec, httptest.NewRequest("GET", "/diff", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
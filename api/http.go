@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ServeHTTP routes plain HTTP requests to the API handlers
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var resp Response
+
+	route, params, status := match(a.Routes(), r.Method, r.URL.Path)
+	switch status {
+	case http.StatusOK:
+		resp = route.Handler(r.Context(), Request{
+			Method:                r.Method,
+			Path:                  r.URL.Path,
+			PathParameters:        params,
+			QueryStringParameters: flatten(r.URL.Query()),
+			Headers:               flatten(r.Header),
+		})
+	default:
+		resp = errorResponse(status)
+	}
+
+	responseBody, err := json.Marshal(resp.Body)
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	for k, v := range headers {
+		w.Header().Set(k, v)
+	}
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(responseBody)
+}
+
+// match finds the route for a request path. The status is 404 when no
+// resource matches and 405 when the resource matches but the method doesn't.
+func match(routes []Route, method, path string) (Route, map[string]string, int) {
+	status := http.StatusNotFound
+	for _, route := range routes {
+		params, ok := matchResource(route.Resource, path)
+		if !ok {
+			continue
+		}
+		if route.Method != method {
+			status = http.StatusMethodNotAllowed
+			continue
+		}
+		return route, params, http.StatusOK
+	}
+	return Route{}, nil, status
+}
+
+// matchResource matches a path against a resource template, returning the
+// values of its {parameters}
+func matchResource(resource, path string) (map[string]string, bool) {
+	resourceParts := strings.Split(strings.Trim(resource, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(resourceParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, part := range resourceParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			params[part[1:len(part)-1]] = pathParts[i]
+		} else if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// flatten keeps the first value of each key, matching API Gateway's
+// single-value parameter maps
+func flatten(values map[string][]string) map[string]string {
+	flat := make(map[string]string, len(values))
+	for k, v := range values {
+		if len(v) > 0 {
+			flat[k] = v[0]
+		}
+	}
+	return flat
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// headers are returned on every response
+var headers = map[string]string{
+	"Content-Type":                     "application/json",
+	"Access-Control-Allow-Origin":      "*",
+	"Access-Control-Allow-Credentials": "true",
+	"Access-Control-Expose-Headers":    "Age, Retry-After",
+}
+
+// LambdaHandler adapts a handler to API Gateway proxy events
+func LambdaHandler(h HandlerFunc) func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		resp := h(ctx, Request{
+			Method:                event.HTTPMethod,
+			Path:                  event.Path,
+			PathParameters:        event.PathParameters,
+			QueryStringParameters: event.QueryStringParameters,
+			Headers:               event.Headers,
+		})
+
+		return lambdaResponse(resp)
+	}
+}
+
+// LambdaRouter dispatches API Gateway proxy events to the route declared for
+// their resource and method. Events from a greedy {proxy+} resource are
+// matched on their path instead, so new routes need no template change.
+func LambdaRouter(routes []Route) func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		for _, route := range routes {
+			if route.Resource == event.Resource && route.Method == event.HTTPMethod {
+				return LambdaHandler(route.Handler)(ctx, event)
+			}
+		}
+
+		route, params, status := match(routes, event.HTTPMethod, event.Path)
+		if status != http.StatusOK {
+			return lambdaResponse(errorResponse(status))
+		}
+
+		event.PathParameters = params
+		return LambdaHandler(route.Handler)(ctx, event)
+	}
+}
+
+// lambdaResponse encodes a response for API Gateway
+func lambdaResponse(resp Response) (events.APIGatewayProxyResponse, error) {
+	responseBody, err := json.Marshal(resp.Body)
+	if err != nil {
+		return events.APIGatewayProxyResponse{}, err
+	}
+
+	responseHeaders := make(map[string]string, len(headers)+len(resp.Headers))
+	for k, v := range headers {
+		responseHeaders[k] = v
+	}
+	for k, v := range resp.Headers {
+		responseHeaders[k] = v
+	}
+
+	return events.APIGatewayProxyResponse{
+		Body:       string(responseBody),
+		StatusCode: resp.StatusCode,
+		Headers:    responseHeaders,
+	}, nil
+}
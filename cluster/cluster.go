@@ -1,9 +1,38 @@
 package cluster
 
-// Cluster contains data for the normalized cluster
+import "time"
+
+// Cluster contains data for the normalized cluster. Metadata a scheduler
+// doesn't report and counts that weren't taken are omitted; a count of zero
+// is kept.
 type Cluster struct {
-	Name      string `json:"name"`
-	Arn       string `json:"arn"`
-	Scheduler string `json:"scheduler"`
-	Status    string `json:"status"`
+	Name            string            `json:"name"`
+	Arn             string            `json:"arn"`
+	Scheduler       string            `json:"scheduler"`
+	Status          string            `json:"status"`
+	Region          string            `json:"region,omitempty"`
+	AccountID       string            `json:"accountId,omitempty"`
+	Version         string            `json:"version,omitempty"`
+	PlatformVersion string            `json:"platformVersion,omitempty"`
+	Endpoint        string            `json:"endpoint,omitempty"`
+	CreatedAt       *time.Time        `json:"createdAt,omitempty"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	RegisteredNodes *int64            `json:"registeredNodes,omitempty"`
+	RunningTasks    *int64            `json:"runningTasks,omitempty"`
+	PendingTasks    *int64            `json:"pendingTasks,omitempty"`
+	ActiveServices  *int64            `json:"activeServices,omitempty"`
+}
+
+// Reference returns the fields that identify a cluster, for the nodes,
+// services and tasks that belong to it. Counts and tags are left out so they
+// don't change with every task that starts elsewhere in the cluster.
+func (c Cluster) Reference() Cluster {
+	return Cluster{
+		Name:      c.Name,
+		Arn:       c.Arn,
+		Scheduler: c.Scheduler,
+		Status:    c.Status,
+		Region:    c.Region,
+		AccountID: c.AccountID,
+	}
 }
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/provider"
+)
+
+// listFlags are shared by the clusters, nodes, services, tasks and workloads
+// commands
+type listFlags struct {
+	scheduler string
+	cluster   string
+	output    string
+}
+
+func parseListFlags(name string, args []string, withCluster bool) listFlags {
+	var f listFlags
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.StringVar(&f.scheduler, "scheduler", "", "only show resources from this scheduler (ecs, eks)")
+	if withCluster {
+		flags.StringVar(&f.cluster, "cluster", "", "only show resources from the cluster with this name")
+	}
+	flags.IntVar(&provider.DefaultConcurrency, "concurrency", provider.DefaultConcurrency, "clusters discovered at once")
+	flags.Var(namespacesFlag{&provider.DefaultNamespaceFilter.Include}, "namespaces", "only discover Kubernetes resources in these comma-separated namespaces")
+	flags.Var(namespacesFlag{&provider.DefaultNamespaceFilter.Exclude}, "exclude-namespaces", "never discover Kubernetes resources in these comma-separated namespaces")
+	flags.StringVar(&f.output, "o", "table", "output format: table, json or yaml")
+	flags.StringVar(&f.output, "output", "table", "output format: table, json or yaml")
+	flags.Parse(args)
+	return f
+}
+
+// namespacesFlag sets a list of namespaces from a comma-separated flag
+type namespacesFlag struct {
+	namespaces *[]string
+}
+
+func (f namespacesFlag) String() string {
+	if f.namespaces == nil {
+		return ""
+	}
+	return strings.Join(*f.namespaces, ",")
+}
+
+func (f namespacesFlag) Set(s string) error {
+	*f.namespaces = provider.ParseNamespaces(s)
+	return nil
+}
+
+// providers returns the providers selected by the scheduler and cluster flags
+func (f listFlags) providers() (provider.Set, error) {
+	providers := newProviders()
+	if f.scheduler != "" {
+		p, ok := providers.Get(f.scheduler)
+		if !ok {
+			return nil, fmt.Errorf("unknown scheduler %q", f.scheduler)
+		}
+		providers = provider.Set{p}
+	}
+	if f.cluster != "" {
+		for i := range providers {
+			providers[i] = clusterFilter{Provider: providers[i], name: f.cluster}
+		}
+	}
+	return providers, nil
+}
+
+// clusterFilter limits a provider to the clusters with a given name
+type clusterFilter struct {
+	provider.Provider
+	name string
+}
+
+func (f clusterFilter) ListClusters(ctx context.Context) ([]cluster.Cluster, error) {
+	clusters, err := f.Provider.ListClusters(ctx)
+	filtered := []cluster.Cluster{}
+	for _, c := range clusters {
+		if c.Name == f.name {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered, err
+}
+
+// warn reports discovery errors without discarding the partial results
+func warn(errs []*provider.Error) {
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, "harbormaster: warning:", err)
+	}
+}
+
+// listClusters prints the clusters of every scheduler
+func listClusters(args []string) error {
+	f := parseListFlags("clusters", args, false)
+	ctx := context.Background()
+
+	providers, err := f.providers()
+	if err != nil {
+		return err
+	}
+
+	clusters, errs := providers.ListClusters(ctx)
+	warn(errs)
+	clusters, errs = providers.CountClusters(ctx, clusters)
+	warn(errs)
+
+	return write(os.Stdout, f.output, clusters, func() table {
+		t := table{header: []string{"SCHEDULER", "NAME", "STATUS", "REGION", "VERSION", "NODES", "TASKS", "SERVICES", "ARN"}}
+		for _, c := range clusters {
+			t.rows = append(t.rows, []string{
+				c.Scheduler, c.Name, c.Status, c.Region, c.Version,
+				count(c.RegisteredNodes),
+				count(c.RunningTasks),
+				count(c.ActiveServices),
+				c.Arn,
+			})
+		}
+		return t
+	})
+}
+
+// count formats a count, or "-" when it wasn't taken
+func count(n *int64) string {
+	if n == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*n, 10)
+}
+
+// listNodes prints the nodes of every cluster
+func listNodes(args []string) error {
+	f := parseListFlags("nodes", args, true)
+	ctx := context.Background()
+
+	providers, err := f.providers()
+	if err != nil {
+		return err
+	}
+
+	nodes, errs := providers.ListNodes(ctx)
+	warn(errs)
+
+	return write(os.Stdout, f.output, nodes, func() table {
+		t := table{header: []string{"SCHEDULER", "CLUSTER", "NAME", "INSTANCE", "TYPE", "ZONE", "STATUS", "CPU FREE", "MEMORY FREE"}}
+		for _, n := range nodes {
+			t.rows = append(t.rows, []string{
+				n.Scheduler, n.Cluster.Name, n.Name, n.InstanceID, n.InstanceType, n.AvailabilityZone, n.Status,
+				free(n, fmt.Sprintf("%dm/%dm", n.Remaining.CPU, n.Registered.CPU)),
+				free(n, fmt.Sprintf("%dMi/%dMi", n.Remaining.Memory, n.Registered.Memory)),
+			})
+		}
+		return t
+	})
+}
+
+// free returns the free resources of a node, or nothing for Kubernetes nodes,
+// which report the resources they can allocate rather than those left
+func free(n node.Node, resources string) string {
+	if n.Scheduler == "eks" {
+		return ""
+	}
+	return resources
+}
+
+// listServices prints the services of every cluster
+func listServices(args []string) error {
+	f := parseListFlags("services", args, true)
+	ctx := context.Background()
+
+	providers, err := f.providers()
+	if err != nil {
+		return err
+	}
+
+	services, errs := providers.ListServices(ctx)
+	warn(errs)
+
+	return write(os.Stdout, f.output, services, func() table {
+		t := table{header: []string{"SCHEDULER", "CLUSTER", "NAMESPACE", "NAME", "STATUS", "LAUNCH TYPE", "RUNNING", "DESIRED", "PORTS"}}
+		for _, s := range services {
+			ports := make([]string, len(s.Ports))
+			for i, p := range s.Ports {
+				ports[i] = fmt.Sprintf("%d/%s", p.Port, p.Protocol)
+			}
+			t.rows = append(t.rows, []string{
+				s.Scheduler, s.Cluster.Name, s.Namespace, s.Name, s.Status, s.LaunchType,
+				strconv.FormatInt(s.RunningCount, 10),
+				strconv.FormatInt(s.DesiredCount, 10),
+				strings.Join(ports, ","),
+			})
+		}
+		return t
+	})
+}
+
+// listTasks prints the tasks and pods of every cluster
+func listTasks(args []string) error {
+	f := parseListFlags("tasks", args, true)
+	ctx := context.Background()
+
+	providers, err := f.providers()
+	if err != nil {
+		return err
+	}
+
+	tasks, errs := providers.ListTasks(ctx)
+	warn(errs)
+
+	return write(os.Stdout, f.output, tasks, func() table {
+		t := table{header: []string{"SCHEDULER", "CLUSTER", "NAMESPACE", "ID", "SERVICE", "STATUS", "LAUNCH TYPE"}}
+		for _, k := range tasks {
+			owner := k.Service
+			if owner == "" {
+				owner = k.Owner
+			}
+			t.rows = append(t.rows, []string{k.Scheduler, k.Cluster.Name, k.Namespace, k.ID, owner, k.LastStatus, k.LaunchType})
+		}
+		return t
+	})
+}
+
+// listWorkloads prints the ECS services and Kubernetes workload controllers of
+// every cluster
+func listWorkloads(args []string) error {
+	f := parseListFlags("workloads", args, true)
+	ctx := context.Background()
+
+	providers, err := f.providers()
+	if err != nil {
+		return err
+	}
+
+	workloads, errs := providers.ListWorkloads(ctx)
+	warn(errs)
+
+	return write(os.Stdout, f.output, workloads, func() table {
+		t := table{header: []string{"SCHEDULER", "CLUSTER", "NAMESPACE", "KIND", "NAME", "STATUS", "READY", "UP-TO-DATE", "SERVICES"}}
+		for _, w := range workloads {
+			t.rows = append(t.rows, []string{
+				w.Scheduler, w.Cluster.Name, w.Namespace, w.Kind, w.Name, w.Status,
+				fmt.Sprintf("%d/%d", w.RunningCount, w.DesiredCount),
+				strconv.FormatInt(w.UpdatedCount, 10),
+				strings.Join(w.Services, ","),
+			})
+		}
+		return t
+	})
+}
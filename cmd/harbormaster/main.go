@@ -0,0 +1,77 @@
+// Command harbormaster runs Harbormaster outside of AWS Lambda.
+//
+// Usage:
+//
+//	harbormaster serve [-addr :8080] [-snapshot-dir dir | -snapshot-db file] [-snapshot-interval 5m] [-snapshot-retention 168h]
+//	harbormaster clusters [-scheduler ecs|eks] [-o table|json|yaml]
+//	harbormaster nodes [-scheduler ecs|eks] [-cluster name] [-o table|json|yaml]
+//	harbormaster services [-scheduler ecs|eks] [-cluster name] [-o table|json|yaml]
+//	harbormaster tasks [-scheduler ecs|eks] [-cluster name] [-o table|json|yaml]
+//	harbormaster workloads [-scheduler ecs|eks] [-cluster name] [-o table|json|yaml]
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/buzzsurfr/harbormaster/provider"
+	"github.com/buzzsurfr/harbormaster/upstream"
+)
+
+// commands maps subcommand names to their implementations
+var commands = map[string]func(args []string) error{
+	"serve":     serve,
+	"clusters":  listClusters,
+	"nodes":     listNodes,
+	"services":  listServices,
+	"tasks":     listTasks,
+	"workloads": listWorkloads,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: harbormaster <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  serve       serve the Harbormaster API over HTTP")
+	fmt.Fprintln(os.Stderr, "  clusters    list ECS and EKS clusters")
+	fmt.Fprintln(os.Stderr, "  nodes       list container instances and Kubernetes nodes")
+	fmt.Fprintln(os.Stderr, "  services    list ECS and Kubernetes services")
+	fmt.Fprintln(os.Stderr, "  tasks       list ECS tasks and Kubernetes pods")
+	fmt.Fprintln(os.Stderr, "  workloads   list ECS services and Kubernetes workload controllers")
+}
+
+// newProviders returns the ECS and EKS providers using the local AWS
+// credentials and shared config
+func newProviders() provider.Set {
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		Config:            *request.WithRetryer(aws.NewConfig(), upstream.NewAWSRetryer()),
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+
+	ec2Svc := ec2.New(sess)
+	return provider.Set{provider.NewECS(ecs.New(sess)).WithEC2(ec2Svc), provider.NewEKS(eks.New(sess)).WithEC2(ec2Svc)}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := command(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "harbormaster:", err)
+		os.Exit(1)
+	}
+}
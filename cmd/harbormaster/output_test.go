@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	clusters := []cluster.Cluster{
+		{Name: "default", Arn: "arn:aws:ecs:us-east-1:123456789012:cluster/default", Scheduler: "ecs", Status: "ACTIVE"},
+		{Name: "production", Scheduler: "eks", Status: "ACTIVE"},
+	}
+	clusterTable := func() table {
+		t := table{header: []string{"SCHEDULER", "NAME", "STATUS", "ARN"}}
+		for _, c := range clusters {
+			t.rows = append(t.rows, []string{c.Scheduler, c.Name, c.Status, c.Arn})
+		}
+		return t
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, write(&buf, "table", clusters, clusterTable))
+	assert.Equal(t, "SCHEDULER  NAME        STATUS  ARN\n"+
+		"ecs        default     ACTIVE  arn:aws:ecs:us-east-1:123456789012:cluster/default\n"+
+		"eks        production  ACTIVE  -\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, write(&buf, "yaml", clusters[1:], clusterTable))
+	assert.Equal(t, "- arn: \"\"\n  name: production\n  scheduler: eks\n  status: ACTIVE\n", buf.String())
+
+	buf.Reset()
+	assert.NotNil(t, write(&buf, "xml", clusters, clusterTable))
+}
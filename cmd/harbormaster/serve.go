@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/api"
+	"github.com/buzzsurfr/harbormaster/provider"
+	"github.com/buzzsurfr/harbormaster/snapshot"
+)
+
+// serve runs the API as a standalone HTTP server
+func serve(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", defaultAddr(), "address to listen on")
+	flags.IntVar(&provider.DefaultConcurrency, "concurrency", provider.DefaultConcurrency, "clusters discovered at once")
+	flags.Var(namespacesFlag{&provider.DefaultNamespaceFilter.Include}, "namespaces", "only discover Kubernetes resources in these comma-separated namespaces")
+	flags.Var(namespacesFlag{&provider.DefaultNamespaceFilter.Exclude}, "exclude-namespaces", "never discover Kubernetes resources in these comma-separated namespaces")
+	snapshotDir := flags.String("snapshot-dir", "", "collect snapshots into this directory and serve the latest one")
+	snapshotDB := flags.String("snapshot-db", "", "collect snapshots into this database file and serve the latest one and their history")
+	snapshotInterval := flags.Duration("snapshot-interval", 5*time.Minute, "time between snapshots")
+	snapshotRetention := flags.Duration("snapshot-retention", 7*24*time.Hour, "time -snapshot-db keeps snapshots for, or 0 to keep them all")
+	flags.Parse(args)
+	if *snapshotDB != "" && *snapshotDir != "" {
+		return errors.New("-snapshot-db and -snapshot-dir can't be used together")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var store snapshot.Store
+	var err error
+	switch {
+	case *snapshotDB != "":
+		var db *snapshot.BoltStore
+		db, err = snapshot.OpenBoltStore(*snapshotDB)
+		if err == nil {
+			store = db.WithRetention(*snapshotRetention)
+		}
+	case *snapshotDir != "":
+		store, err = snapshot.NewFileStore(*snapshotDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	live := newProviders()
+	providers := live.Cached(provider.DefaultCacheTTL)
+	if store != nil {
+		collector := &snapshot.Collector{Providers: live, Store: store, Interval: *snapshotInterval}
+		go collector.Run(ctx)
+		providers = snapshot.NewReader(store).Providers("ecs", "eks")
+	}
+
+	handler := api.New(providers)
+	if history, ok := store.(snapshot.History); ok {
+		handler.WithHistory(history)
+	}
+
+	// Retry counts and other expvar metrics are served at /debug/vars
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/", handler)
+
+	server := &http.Server{
+		Addr:    *addr,
+		Handler: mux,
+	}
+
+	// Shut down gracefully when the container is stopped
+	done := make(chan error, 1)
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		done <- server.Shutdown(ctx)
+	}()
+
+	log.Printf("listening on %s", *addr)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return <-done
+}
+
+// defaultAddr listens on $PORT when set, as most container platforms expect
+func defaultAddr() string {
+	if port := os.Getenv("PORT"); port != "" {
+		return ":" + port
+	}
+	return ":8080"
+}
@@ -4,10 +4,35 @@ import "github.com/buzzsurfr/harbormaster/cluster"
 
 // Node contains data for the normalized container instance/node
 type Node struct {
-	Name       string `json:"name"`
-	Arn        string `json:"arn"`
-	InstanceID string `json:"instanceId"`
-	Scheduler  string `json:"scheduler"`
-	Status     string `json:"status"`
-	Cluster    cluster.Cluster
+	Name             string            `json:"name"`
+	UID              string            `json:"uid"`
+	Arn              string            `json:"arn"`
+	InstanceID       string            `json:"instanceId"`
+	Scheduler        string            `json:"scheduler"`
+	Status           string            `json:"status"`
+	InstanceType     string            `json:"instanceType"`
+	AvailabilityZone string            `json:"availabilityZone"`
+	PrivateIP        string            `json:"privateIp"`
+	AgentVersion     string            `json:"agentVersion"`
+	OS               string            `json:"os"`
+	AMI              string            `json:"ami"`
+	Labels           map[string]string `json:"labels"`
+	Registered       Resources         `json:"registered"`
+	Remaining        Resources         `json:"remaining"`
+	Cluster          cluster.Cluster
+}
+
+// Resources are the CPU, in millicores, and memory, in MiB, of a node
+type Resources struct {
+	CPU    int64 `json:"cpu"`
+	Memory int64 `json:"memory"`
+}
+
+// Matches reports whether id is the name, UID, EC2 instance ID or private IP
+// of the node
+func (n Node) Matches(id string) bool {
+	if id == "" {
+		return false
+	}
+	return id == n.Name || id == n.UID || id == n.InstanceID || id == n.PrivateIP
 }
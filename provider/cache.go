@@ -0,0 +1,346 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/buzzsurfr/harbormaster/workload"
+)
+
+// CacheTTL sets how long each kind of result is kept. A zero TTL disables
+// caching for that kind.
+type CacheTTL struct {
+	Clusters time.Duration
+	Nodes    time.Duration
+	Services time.Duration
+	Tasks    time.Duration
+}
+
+// DefaultCacheTTL is used by the Harbormaster binaries. Every TTL is read
+// from the HARBORMASTER_CACHE_TTL environment variable when set, e.g. "1m".
+var DefaultCacheTTL = CacheTTL{
+	Clusters: 5 * time.Minute,
+	Nodes:    30 * time.Second,
+	Services: 30 * time.Second,
+	Tasks:    30 * time.Second,
+}
+
+// Cache is a provider that keeps the results of another provider, by
+// cluster, until they expire. Callers that miss the same result at once
+// share a single call to the provider.
+type Cache struct {
+	Provider
+	ttl CacheTTL
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	calls   map[string]*cacheCall
+}
+
+// cacheEntry is a cached result, the time it was discovered and the time it
+// expires
+type cacheEntry struct {
+	value      interface{}
+	discovered time.Time
+	expires    time.Time
+}
+
+// cacheCall is a call to the provider in flight, waited on by every caller
+// that misses the same key meanwhile
+type cacheCall struct {
+	done       chan struct{}
+	value      interface{}
+	err        error
+	discovered time.Time
+}
+
+// NewCache returns a provider that caches the results of p
+func NewCache(p Provider, ttl CacheTTL) *Cache {
+	return &Cache{
+		Provider: p,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  map[string]cacheEntry{},
+		calls:    map[string]*cacheCall{},
+	}
+}
+
+// Cached wraps every provider of the set in a cache
+func (s Set) Cached(ttl CacheTTL) Set {
+	cached := make(Set, len(s))
+	for i, p := range s {
+		cached[i] = NewCache(p, ttl)
+	}
+	return cached
+}
+
+// ListClusters returns the cached clusters or lists them
+func (c *Cache) ListClusters(ctx context.Context) ([]cluster.Cluster, error) {
+	v, err := c.get(ctx, "clusters", c.ttl.Clusters, func(ctx context.Context) (interface{}, error) {
+		return c.Provider.ListClusters(ctx)
+	})
+	result, _ := v.([]cluster.Cluster)
+	if result != nil {
+		result = append(make([]cluster.Cluster, 0, len(result)), result...)
+	}
+	return result, err
+}
+
+// DescribeCluster returns the cached cluster or describes it
+func (c *Cache) DescribeCluster(ctx context.Context, name string) (cluster.Cluster, error) {
+	v, err := c.get(ctx, "cluster/"+name, c.ttl.Clusters, func(ctx context.Context) (interface{}, error) {
+		return c.Provider.DescribeCluster(ctx, name)
+	})
+	result, _ := v.(cluster.Cluster)
+	return result, err
+}
+
+// CountCluster returns the cached counts of a cluster or counts them, when
+// the cached provider is a Counter. Counts change with every task, so they
+// are kept as long as tasks.
+func (c *Cache) CountCluster(ctx context.Context, cl cluster.Cluster) (cluster.Cluster, error) {
+	counter, ok := c.Provider.(Counter)
+	if !ok {
+		return cl, nil
+	}
+
+	v, err := c.get(ctx, "count/"+cl.Name, c.ttl.Tasks, func(ctx context.Context) (interface{}, error) {
+		return counter.CountCluster(ctx, cl)
+	})
+	result, _ := v.(cluster.Cluster)
+	return result, err
+}
+
+// ListNodes returns the cached nodes of a cluster or lists them
+func (c *Cache) ListNodes(ctx context.Context, cl cluster.Cluster) ([]node.Node, error) {
+	v, err := c.get(ctx, "nodes/"+cl.Name, c.ttl.Nodes, func(ctx context.Context) (interface{}, error) {
+		return c.Provider.ListNodes(ctx, cl)
+	})
+	result, _ := v.([]node.Node)
+	if result != nil {
+		result = append(make([]node.Node, 0, len(result)), result...)
+	}
+	return result, err
+}
+
+// DescribeNode returns the cached node or describes it
+func (c *Cache) DescribeNode(ctx context.Context, cl cluster.Cluster, name string) (node.Node, error) {
+	v, err := c.get(ctx, "node/"+cl.Name+"/"+name, c.ttl.Nodes, func(ctx context.Context) (interface{}, error) {
+		return c.Provider.DescribeNode(ctx, cl, name)
+	})
+	result, _ := v.(node.Node)
+	return result, err
+}
+
+// ListServices returns the cached services of a cluster or lists them
+func (c *Cache) ListServices(ctx context.Context, cl cluster.Cluster) ([]service.Service, error) {
+	v, err := c.get(ctx, "services/"+cl.Name, c.ttl.Services, func(ctx context.Context) (interface{}, error) {
+		return c.Provider.ListServices(ctx, cl)
+	})
+	result, _ := v.([]service.Service)
+	if result != nil {
+		result = append(make([]service.Service, 0, len(result)), result...)
+	}
+	return result, err
+}
+
+// DescribeService returns the cached service detail or describes it
+func (c *Cache) DescribeService(ctx context.Context, cl cluster.Cluster, namespace, name string) (service.Detail, error) {
+	v, err := c.get(ctx, "service/"+cl.Name+"/"+namespace+"/"+name, c.ttl.Services, func(ctx context.Context) (interface{}, error) {
+		return c.Provider.DescribeService(ctx, cl, namespace, name)
+	})
+	result, _ := v.(service.Detail)
+	return result, err
+}
+
+// ListTasks returns the cached tasks of a cluster or lists them
+func (c *Cache) ListTasks(ctx context.Context, cl cluster.Cluster) ([]task.Task, error) {
+	v, err := c.get(ctx, "tasks/"+cl.Name, c.ttl.Tasks, func(ctx context.Context) (interface{}, error) {
+		return c.Provider.ListTasks(ctx, cl)
+	})
+	result, _ := v.([]task.Task)
+	if result != nil {
+		result = append(make([]task.Task, 0, len(result)), result...)
+	}
+	return result, err
+}
+
+// DescribeTask returns the cached task or describes it
+func (c *Cache) DescribeTask(ctx context.Context, cl cluster.Cluster, id string) (task.Task, error) {
+	v, err := c.get(ctx, "task/"+cl.Name+"/"+id, c.ttl.Tasks, func(ctx context.Context) (interface{}, error) {
+		return c.Provider.DescribeTask(ctx, cl, id)
+	})
+	result, _ := v.(task.Task)
+	return result, err
+}
+
+// ListWorkloads returns the cached workloads of a cluster or lists them.
+// Workloads are kept as long as services.
+func (c *Cache) ListWorkloads(ctx context.Context, cl cluster.Cluster) ([]workload.Workload, error) {
+	v, err := c.get(ctx, "workloads/"+cl.Name, c.ttl.Services, func(ctx context.Context) (interface{}, error) {
+		return c.Provider.ListWorkloads(ctx, cl)
+	})
+	result, _ := v.([]workload.Workload)
+	if result != nil {
+		result = append(make([]workload.Workload, 0, len(result)), result...)
+	}
+	return result, err
+}
+
+// fetchTimeout bounds a fetch shared by callers that miss the same key, which
+// doesn't end when the caller that started it gives up
+var fetchTimeout = 30 * time.Second
+
+// get returns the entry for key while it is younger than ttl, otherwise the
+// result of fetch. A miss waits for a fetch of the same key already in
+// flight rather than starting another. Errors aren't cached; partial results
+// returned along with an error are passed on without being cached. Slices in
+// the result are shared with the cache and must be copied before they are
+// returned.
+func (c *Cache) get(ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if ttl == 0 || refresh(ctx) {
+		v, discovered, err := c.fetch(ctx, key, ttl, fetch)
+		if err == nil {
+			Observe(ctx, discovered)
+		}
+		return v, err
+	}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && c.now().Before(e.expires) {
+		c.mu.Unlock()
+		Observe(ctx, e.discovered)
+		return e.value, nil
+	}
+	call, ok := c.calls[key]
+	if !ok {
+		call = &cacheCall{done: make(chan struct{})}
+		c.calls[key] = call
+		go c.share(ctx, call, key, ttl, fetch)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err == nil {
+		Observe(ctx, call.discovered)
+	}
+	return call.value, call.err
+}
+
+// share fetches a key for every caller waiting on call. The fetch keeps the
+// values of the context of the caller that started it but not its
+// cancellation, so the other callers still get a result when that caller
+// gives up or disconnects.
+func (c *Cache) share(ctx context.Context, call *cacheCall, key string, ttl time.Duration, fetch func(ctx context.Context) (interface{}, error)) {
+	ctx, cancel := context.WithTimeout(detachedContext{ctx}, fetchTimeout)
+	defer cancel()
+	call.value, call.discovered, call.err = c.fetch(ctx, key, ttl, fetch)
+
+	c.mu.Lock()
+	delete(c.calls, key)
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// fetch calls fetch and keeps a successful result for ttl. Expired entries
+// are dropped whenever a result is kept, so results that aren't asked for
+// again don't pile up.
+func (c *Cache) fetch(ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context) (interface{}, error)) (interface{}, time.Time, error) {
+	discovered := c.now()
+	v, err := fetch(ctx)
+	if err != nil {
+		return v, discovered, err
+	}
+
+	if ttl > 0 {
+		c.mu.Lock()
+		for k, e := range c.entries {
+			if !discovered.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.entries[key] = cacheEntry{value: v, discovered: discovered, expires: discovered.Add(ttl)}
+		c.mu.Unlock()
+	}
+	return v, discovered, nil
+}
+
+// detachedContext carries the values of a context without its deadline or
+// cancellation
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+type refreshKey struct{}
+
+// WithRefresh returns a context that bypasses cached results. Results
+// discovered with it replace the cached ones.
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey{}, true)
+}
+
+// refresh reports whether ctx bypasses cached results
+func refresh(ctx context.Context) bool {
+	v, _ := ctx.Value(refreshKey{}).(bool)
+	return v
+}
+
+// Freshness records when the oldest result returned through a context was
+// discovered
+type Freshness struct {
+	mu     sync.Mutex
+	oldest time.Time
+}
+
+type freshnessKey struct{}
+
+// WithFreshness returns a context that records the discovery time of every
+// cached or fresh result returned with it
+func WithFreshness(ctx context.Context) (context.Context, *Freshness) {
+	f := &Freshness{}
+	return context.WithValue(ctx, freshnessKey{}, f), f
+}
+
+// Discovered returns when the oldest result was discovered, or the zero time
+// when no result was recorded
+func (f *Freshness) Discovered() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.oldest
+}
+
+// Observe records the discovery time of a result in the Freshness carried
+// by ctx
+func Observe(ctx context.Context, discovered time.Time) {
+	f, ok := ctx.Value(freshnessKey{}).(*Freshness)
+	if !ok {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.oldest.IsZero() || discovered.Before(f.oldest) {
+		f.oldest = discovered
+	}
+}
+
+func init() {
+	if ttl, err := time.ParseDuration(os.Getenv("HARBORMASTER_CACHE_TTL")); err == nil && ttl >= 0 {
+		DefaultCacheTTL = CacheTTL{Clusters: ttl, Nodes: ttl, Services: ttl, Tasks: ttl}
+	}
+}
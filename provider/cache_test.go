@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingProvider counts the calls that reach the provider it wraps
+type countingProvider struct {
+	Provider
+	listNodesCalls int
+}
+
+func (p *countingProvider) ListNodes(ctx context.Context, c cluster.Cluster) ([]node.Node, error) {
+	p.listNodesCalls++
+	return p.Provider.ListNodes(ctx, c)
+}
+
+func TestCacheExpires(t *testing.T) {
+	prod := cluster.Cluster{Name: "prod", Scheduler: "eks"}
+	counting := &countingProvider{Provider: &fakeProvider{
+		scheduler: "eks",
+		nodes:     map[string][]node.Node{"prod": {{Name: "n-1"}}},
+	}}
+
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewCache(counting, CacheTTL{Nodes: time.Minute})
+	cache.now = func() time.Time { return now }
+
+	nodes, err := cache.ListNodes(context.Background(), prod)
+	assert.Nil(t, err)
+	assert.Equal(t, []node.Node{{Name: "n-1"}}, nodes)
+
+	now = now.Add(30 * time.Second)
+	ctx, freshness := WithFreshness(context.Background())
+	nodes, err = cache.ListNodes(ctx, prod)
+	assert.Nil(t, err)
+	assert.Equal(t, []node.Node{{Name: "n-1"}}, nodes)
+	assert.Equal(t, 1, counting.listNodesCalls)
+	assert.Equal(t, now.Add(-30*time.Second), freshness.Discovered())
+
+	now = now.Add(time.Minute)
+	cache.ListNodes(context.Background(), prod)
+	assert.Equal(t, 2, counting.listNodesCalls)
+}
+
+func TestCacheRefresh(t *testing.T) {
+	prod := cluster.Cluster{Name: "prod", Scheduler: "eks"}
+	counting := &countingProvider{Provider: &fakeProvider{scheduler: "eks"}}
+	cache := NewCache(counting, DefaultCacheTTL)
+
+	cache.ListNodes(context.Background(), prod)
+	cache.ListNodes(WithRefresh(context.Background()), prod)
+	assert.Equal(t, 2, counting.listNodesCalls)
+
+	// The refreshed result replaces the cached one
+	cache.ListNodes(context.Background(), prod)
+	assert.Equal(t, 2, counting.listNodesCalls)
+}
+
+// blockingProvider lists nodes once release is closed, or fails when its
+// context is done first, counting the calls that reach it
+type blockingProvider struct {
+	Provider
+	release chan struct{}
+
+	mu             sync.Mutex
+	listNodesCalls int
+}
+
+func (p *blockingProvider) ListNodes(ctx context.Context, c cluster.Cluster) ([]node.Node, error) {
+	p.mu.Lock()
+	p.listNodesCalls++
+	p.mu.Unlock()
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return p.Provider.ListNodes(ctx, c)
+}
+
+func TestCacheSharesConcurrentMisses(t *testing.T) {
+	prod := cluster.Cluster{Name: "prod", Scheduler: "eks"}
+	blocking := &blockingProvider{
+		Provider: &fakeProvider{scheduler: "eks", nodes: map[string][]node.Node{"prod": {{Name: "n-1"}}}},
+		release:  make(chan struct{}),
+	}
+	cache := NewCache(blocking, DefaultCacheTTL)
+
+	var wg sync.WaitGroup
+	results := make([][]node.Node, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.ListNodes(context.Background(), prod)
+		}(i)
+	}
+
+	// Let every caller miss before the first call returns
+	for {
+		cache.mu.Lock()
+		waiting := len(cache.calls)
+		cache.mu.Unlock()
+		if waiting == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(blocking.release)
+	wg.Wait()
+
+	assert.Equal(t, 1, blocking.listNodesCalls)
+	for _, nodes := range results {
+		assert.Equal(t, []node.Node{{Name: "n-1"}}, nodes)
+	}
+}
+
+func TestCacheSharedMissOutlivesCaller(t *testing.T) {
+	prod := cluster.Cluster{Name: "prod", Scheduler: "eks"}
+	blocking := &blockingProvider{
+		Provider: &fakeProvider{scheduler: "eks", nodes: map[string][]node.Node{"prod": {{Name: "n-1"}}}},
+		release:  make(chan struct{}),
+	}
+	cache := NewCache(blocking, DefaultCacheTTL)
+
+	// The caller that starts the call disconnects while it is in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := cache.ListNodes(ctx, prod)
+		leader <- err
+	}()
+	for {
+		cache.mu.Lock()
+		waiting := len(cache.calls)
+		cache.mu.Unlock()
+		if waiting == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	assert.Equal(t, context.Canceled, <-leader)
+
+	// Callers still waiting on the call get its result
+	waiter := make(chan []node.Node)
+	go func() {
+		nodes, _ := cache.ListNodes(context.Background(), prod)
+		waiter <- nodes
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(blocking.release)
+	assert.Equal(t, []node.Node{{Name: "n-1"}}, <-waiter)
+	assert.Equal(t, 1, blocking.listNodesCalls)
+}
+
+func TestCacheCopiesAndEvicts(t *testing.T) {
+	prod := cluster.Cluster{Name: "prod", Scheduler: "eks"}
+	counting := &countingProvider{Provider: &fakeProvider{
+		scheduler: "eks",
+		nodes:     map[string][]node.Node{"prod": {{Name: "n-1"}}, "dev": {{Name: "n-2"}}},
+	}}
+
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewCache(counting, CacheTTL{Nodes: time.Minute})
+	cache.now = func() time.Time { return now }
+
+	// Changing a returned slice doesn't change the cached one
+	nodes, _ := cache.ListNodes(context.Background(), prod)
+	nodes[0].Name = "changed"
+	nodes, _ = cache.ListNodes(context.Background(), prod)
+	assert.Equal(t, "n-1", nodes[0].Name)
+
+	// Expired entries are dropped when another result is kept
+	now = now.Add(2 * time.Minute)
+	cache.ListNodes(context.Background(), cluster.Cluster{Name: "dev", Scheduler: "eks"})
+	assert.Len(t, cache.entries, 1)
+	assert.Contains(t, cache.entries, "nodes/dev")
+}
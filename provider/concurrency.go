@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// DefaultConcurrency is the number of clusters discovered at once when the
+// context doesn't carry a limit. It is read from the
+// HARBORMASTER_CONCURRENCY environment variable when set.
+var DefaultConcurrency = 10
+
+type concurrencyKey struct{}
+
+// WithConcurrency returns a context that limits discovery started with it to
+// n concurrent calls at each level of fan-out
+func WithConcurrency(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, concurrencyKey{}, n)
+}
+
+// concurrency returns the fan-out limit carried by ctx
+func concurrency(ctx context.Context) int {
+	if n, ok := ctx.Value(concurrencyKey{}).(int); ok && n > 0 {
+		return n
+	}
+	if DefaultConcurrency > 0 {
+		return DefaultConcurrency
+	}
+	return 1
+}
+
+// forEach calls fn for every index below n, running at most
+// concurrency(ctx) calls at once. Errors are returned by index. Once ctx is
+// done no new calls are started and the remaining indexes get ctx.Err().
+func forEach(ctx context.Context, n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, concurrency(ctx))
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			for j := i; j < n; j++ {
+				errs[j] = err
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(i)
+		}(i)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+func init() {
+	if n, err := strconv.Atoi(os.Getenv("HARBORMASTER_CONCURRENCY")); err == nil && n > 0 {
+		DefaultConcurrency = n
+	}
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForEachLimitsConcurrency(t *testing.T) {
+	ctx := WithConcurrency(context.Background(), 3)
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	results := make([]int, 20)
+	errs := forEach(ctx, len(results), func(i int) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+		results[i] = i * i
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+
+	assert.Equal(t, make([]error, len(results)), errs)
+	assert.True(t, peak <= 3, "peak concurrency %d exceeds limit", peak)
+	for i, r := range results {
+		assert.Equal(t, i*i, r)
+	}
+}
+
+func TestForEachStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(WithConcurrency(context.Background(), 1))
+
+	started := 0
+	errs := forEach(ctx, 5, func(i int) error {
+		started++
+		if i == 1 {
+			cancel()
+		}
+		return nil
+	})
+
+	assert.Equal(t, 2, started)
+	assert.Nil(t, errs[0])
+	assert.Equal(t, context.Canceled, errs[4])
+}
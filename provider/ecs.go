@@ -0,0 +1,624 @@
+package provider
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/buzzsurfr/harbormaster/workload"
+)
+
+// Maximum number of resources accepted by a single ECS Describe call
+const (
+	ecsDescribeClustersLimit           = 100
+	ecsDescribeContainerInstancesLimit = 100
+	ecsDescribeServicesLimit           = 10
+	ecsDescribeTasksLimit              = 100
+)
+
+// ECS discovers clusters, container instances, services and tasks from
+// Amazon ECS
+type ECS struct {
+	svc ecsiface.ECSAPI
+	ec2 ec2iface.EC2API
+}
+
+// NewECS returns a provider backed by an ECS client
+func NewECS(svc ecsiface.ECSAPI) *ECS {
+	return &ECS{svc: svc}
+}
+
+// WithEC2 looks up the private IP of container instances with an EC2 client
+func (p *ECS) WithEC2(svc ec2iface.EC2API) *ECS {
+	p.ec2 = svc
+	return p
+}
+
+// Scheduler returns "ecs"
+func (p *ECS) Scheduler() string {
+	return "ecs"
+}
+
+func normalizeEcsCluster(ecsCluster *ecs.Cluster) cluster.Cluster {
+	currentCluster := cluster.Cluster{
+		Name:            *ecsCluster.ClusterName,
+		Arn:             *ecsCluster.ClusterArn,
+		Scheduler:       "ecs",
+		Status:          *ecsCluster.Status,
+		Tags:            make(map[string]string, len(ecsCluster.Tags)),
+		RegisteredNodes: ecsCluster.RegisteredContainerInstancesCount,
+		RunningTasks:    ecsCluster.RunningTasksCount,
+		PendingTasks:    ecsCluster.PendingTasksCount,
+		ActiveServices:  ecsCluster.ActiveServicesCount,
+	}
+	currentCluster.Region, currentCluster.AccountID = arnLocation(currentCluster.Arn)
+
+	for _, tag := range ecsCluster.Tags {
+		currentCluster.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return currentCluster
+}
+
+func normalizeEcsNode(ecsNode *ecs.ContainerInstance, privateIPs map[string]string, c cluster.Cluster) node.Node {
+	name := strings.Split(*ecsNode.ContainerInstanceArn, "/")
+	currentNode := node.Node{
+		Name:       name[len(name)-1],
+		Arn:        *ecsNode.ContainerInstanceArn,
+		InstanceID: *ecsNode.Ec2InstanceId,
+		Scheduler:  "ecs",
+		Status:     *ecsNode.Status,
+		PrivateIP:  privateIPs[*ecsNode.Ec2InstanceId],
+		Labels:     make(map[string]string, len(ecsNode.Attributes)),
+		Registered: ecsResources(ecsNode.RegisteredResources),
+		Remaining:  ecsResources(ecsNode.RemainingResources),
+		Cluster:    c.Reference(),
+	}
+
+	if ecsNode.VersionInfo != nil {
+		currentNode.AgentVersion = aws.StringValue(ecsNode.VersionInfo.AgentVersion)
+	}
+
+	// Placement and platform details are reported as attributes
+	for _, attribute := range ecsNode.Attributes {
+		currentNode.Labels[aws.StringValue(attribute.Name)] = aws.StringValue(attribute.Value)
+	}
+	currentNode.InstanceType = currentNode.Labels["ecs.instance-type"]
+	currentNode.AvailabilityZone = currentNode.Labels["ecs.availability-zone"]
+	currentNode.OS = currentNode.Labels["ecs.os-type"]
+	currentNode.AMI = currentNode.Labels["ecs.ami-id"]
+
+	return currentNode
+}
+
+// ecsResources returns the CPU and memory among the resources of a container
+// instance
+func ecsResources(ecsResources []*ecs.Resource) node.Resources {
+	var resources node.Resources
+	for _, resource := range ecsResources {
+		switch aws.StringValue(resource.Name) {
+		case "CPU":
+			resources.CPU = aws.Int64Value(resource.IntegerValue) * 1000 / 1024
+		case "MEMORY":
+			resources.Memory = aws.Int64Value(resource.IntegerValue)
+		}
+	}
+	return resources
+}
+
+func normalizeEcsService(ecsService *ecs.Service, c cluster.Cluster) service.Service {
+	launchType, capacityProvider := ecsLaunchType(ecsService.LaunchType, ecsService.CapacityProviderStrategy)
+	currentService := service.Service{
+		Name:             *ecsService.ServiceName,
+		Arn:              *ecsService.ServiceArn,
+		Status:           *ecsService.Status,
+		Cluster:          c.Reference(),
+		Scheduler:        "ecs",
+		LaunchType:       launchType,
+		CapacityProvider: capacityProvider,
+		Namespace:        "",
+		DesiredCount:     aws.Int64Value(ecsService.DesiredCount),
+		RunningCount:     aws.Int64Value(ecsService.RunningCount),
+		PendingCount:     aws.Int64Value(ecsService.PendingCount),
+		TaskDefinition:   aws.StringValue(ecsService.TaskDefinition),
+		Ports:            ecsPorts(ecsService),
+		LoadBalancers:    make([]service.LoadBalancer, len(ecsService.LoadBalancers)),
+	}
+
+	for i, ecsLoadBalancer := range ecsService.LoadBalancers {
+		currentService.LoadBalancers[i] = service.LoadBalancer{
+			Name:           aws.StringValue(ecsLoadBalancer.LoadBalancerName),
+			TargetGroupArn: aws.StringValue(ecsLoadBalancer.TargetGroupArn),
+			ContainerName:  aws.StringValue(ecsLoadBalancer.ContainerName),
+			ContainerPort:  aws.Int64Value(ecsLoadBalancer.ContainerPort),
+		}
+	}
+
+	return currentService
+}
+
+// ecsPorts returns the container ports an ECS service exposes through its
+// load balancers and service registries, without duplicates
+func ecsPorts(ecsService *ecs.Service) []service.Port {
+	ports := []service.Port{}
+	seen := map[int64]bool{}
+	add := func(port int64) {
+		if port == 0 || seen[port] {
+			return
+		}
+		seen[port] = true
+		ports = append(ports, service.Port{Protocol: "TCP", Port: port, TargetPort: strconv.FormatInt(port, 10)})
+	}
+
+	for _, ecsLoadBalancer := range ecsService.LoadBalancers {
+		add(aws.Int64Value(ecsLoadBalancer.ContainerPort))
+	}
+	for _, registry := range ecsService.ServiceRegistries {
+		// SRV records name the port, A records only the container port
+		if registry.Port != nil {
+			add(aws.Int64Value(registry.Port))
+		} else {
+			add(aws.Int64Value(registry.ContainerPort))
+		}
+	}
+	return ports
+}
+
+func normalizeEcsServiceDetail(ecsService *ecs.Service, c cluster.Cluster) service.Detail {
+	detail := service.Detail{
+		Service:     normalizeEcsService(ecsService, c),
+		Deployments: make([]service.Deployment, len(ecsService.Deployments)),
+		Conditions:  []service.Condition{},
+	}
+
+	if ecsService.DeploymentController != nil {
+		detail.DeploymentConfiguration.Strategy = aws.StringValue(ecsService.DeploymentController.Type)
+	}
+	if config := ecsService.DeploymentConfiguration; config != nil {
+		detail.DeploymentConfiguration.MaximumPercent = aws.Int64Value(config.MaximumPercent)
+		detail.DeploymentConfiguration.MinimumHealthyPercent = aws.Int64Value(config.MinimumHealthyPercent)
+		if config.DeploymentCircuitBreaker != nil {
+			detail.DeploymentConfiguration.CircuitBreaker = aws.BoolValue(config.DeploymentCircuitBreaker.Enable)
+			detail.DeploymentConfiguration.Rollback = aws.BoolValue(config.DeploymentCircuitBreaker.Rollback)
+		}
+	}
+
+	for i, ecsDeployment := range ecsService.Deployments {
+		detail.Deployments[i] = service.Deployment{
+			ID:                 aws.StringValue(ecsDeployment.Id),
+			Status:             aws.StringValue(ecsDeployment.Status),
+			TaskDefinition:     aws.StringValue(ecsDeployment.TaskDefinition),
+			DesiredCount:       aws.Int64Value(ecsDeployment.DesiredCount),
+			RunningCount:       aws.Int64Value(ecsDeployment.RunningCount),
+			PendingCount:       aws.Int64Value(ecsDeployment.PendingCount),
+			FailedCount:        aws.Int64Value(ecsDeployment.FailedTasks),
+			RolloutState:       aws.StringValue(ecsDeployment.RolloutState),
+			RolloutStateReason: aws.StringValue(ecsDeployment.RolloutStateReason),
+			CreatedAt:          ecsDeployment.CreatedAt,
+			UpdatedAt:          ecsDeployment.UpdatedAt,
+		}
+	}
+
+	return detail
+}
+
+func normalizeEcsWorkload(ecsService *ecs.Service, c cluster.Cluster) workload.Workload {
+	currentWorkload := workload.Workload{
+		Name:           aws.StringValue(ecsService.ServiceName),
+		Arn:            aws.StringValue(ecsService.ServiceArn),
+		Kind:           "Service",
+		Status:         aws.StringValue(ecsService.Status),
+		Cluster:        c.Reference(),
+		Scheduler:      "ecs",
+		DesiredCount:   aws.Int64Value(ecsService.DesiredCount),
+		RunningCount:   aws.Int64Value(ecsService.RunningCount),
+		PendingCount:   aws.Int64Value(ecsService.PendingCount),
+		TaskDefinition: aws.StringValue(ecsService.TaskDefinition),
+		Services:       []string{aws.StringValue(ecsService.ServiceName)},
+		CreatedAt:      ecsService.CreatedAt,
+	}
+
+	// Tasks of the primary deployment run the current task definition
+	for _, ecsDeployment := range ecsService.Deployments {
+		if aws.StringValue(ecsDeployment.Status) == "PRIMARY" {
+			currentWorkload.UpdatedCount = aws.Int64Value(ecsDeployment.RunningCount)
+		}
+	}
+
+	return currentWorkload
+}
+
+func normalizeEcsTask(ecsTask *ecs.Task, c cluster.Cluster) task.Task {
+	arn := aws.StringValue(ecsTask.TaskArn)
+	currentTask := task.Task{
+		ID:               arn[strings.LastIndex(arn, "/")+1:],
+		Arn:              arn,
+		Scheduler:        "ecs",
+		Cluster:          c.Reference(),
+		DesiredStatus:    aws.StringValue(ecsTask.DesiredStatus),
+		LastStatus:       aws.StringValue(ecsTask.LastStatus),
+		StartedAt:        ecsTask.StartedAt,
+		LaunchType:       strings.ToLower(aws.StringValue(ecsTask.LaunchType)),
+		CapacityProvider: aws.StringValue(ecsTask.CapacityProviderName),
+		Containers:       make([]task.Container, len(ecsTask.Containers)),
+	}
+
+	// Tasks on Fargate have no container instance
+	if containerInstanceArn := aws.StringValue(ecsTask.ContainerInstanceArn); containerInstanceArn != "" {
+		currentTask.Node = containerInstanceArn[strings.LastIndex(containerInstanceArn, "/")+1:]
+	}
+
+	// Tasks started by a service belong to the group "service:<name>"
+	if group := aws.StringValue(ecsTask.Group); strings.HasPrefix(group, "service:") {
+		currentTask.Service = strings.TrimPrefix(group, "service:")
+	}
+
+	for i, ecsContainer := range ecsTask.Containers {
+		currentTask.Containers[i] = normalizeEcsContainer(ecsContainer)
+	}
+
+	return currentTask
+}
+
+func normalizeEcsContainer(ecsContainer *ecs.Container) task.Container {
+	return task.Container{
+		Name:        aws.StringValue(ecsContainer.Name),
+		Image:       aws.StringValue(ecsContainer.Image),
+		ImageDigest: aws.StringValue(ecsContainer.ImageDigest),
+		Status:      aws.StringValue(ecsContainer.LastStatus),
+		ExitCode:    ecsContainer.ExitCode,
+		Health:      aws.StringValue(ecsContainer.HealthStatus),
+		Reservations: task.Resources{
+			CPU:    ecsCPU(aws.StringValue(ecsContainer.Cpu)),
+			Memory: ecsMemory(aws.StringValue(ecsContainer.MemoryReservation)),
+		},
+		Limits: task.Resources{
+			Memory: ecsMemory(aws.StringValue(ecsContainer.Memory)),
+		},
+	}
+}
+
+// ecsCPU converts ECS CPU units, 1024 to a vCPU, to millicores
+func ecsCPU(units string) int64 {
+	n, _ := strconv.ParseInt(units, 10, 64)
+	return n * 1000 / 1024
+}
+
+// ecsMemory parses an ECS memory size, which is already in MiB
+func ecsMemory(mib string) int64 {
+	n, _ := strconv.ParseInt(mib, 10, 64)
+	return n
+}
+
+// ListClusters lists and describes all ECS clusters
+func (p *ECS) ListClusters(ctx context.Context) ([]cluster.Cluster, error) {
+	var clusterArns []*string
+	input := &ecs.ListClustersInput{}
+	for {
+		// ecs:ListClusters
+		resultListClusters, err := p.svc.ListClustersWithContext(ctx, input)
+		if err != nil {
+			return nil, newError("ecs", "", "ecs:ListClusters", err)
+		}
+
+		clusterArns = append(clusterArns, resultListClusters.ClusterArns...)
+		if aws.StringValue(resultListClusters.NextToken) == "" {
+			break
+		}
+		input.NextToken = resultListClusters.NextToken
+	}
+
+	clusters := make([]cluster.Cluster, 0, len(clusterArns))
+	for _, arns := range batch(clusterArns, ecsDescribeClustersLimit) {
+		// ecs:DescribeClusters
+		resultDescribeClusters, err := p.svc.DescribeClustersWithContext(ctx, &ecs.DescribeClustersInput{
+			Clusters: arns,
+			Include:  aws.StringSlice([]string{ecs.ClusterFieldTags}),
+		})
+		if err != nil {
+			return nil, newError("ecs", "", "ecs:DescribeClusters", err)
+		}
+
+		for _, ecsCluster := range resultDescribeClusters.Clusters {
+			clusters = append(clusters, normalizeEcsCluster(ecsCluster))
+		}
+	}
+
+	return clusters, nil
+}
+
+// DescribeCluster describes a single ECS cluster by name or ARN
+func (p *ECS) DescribeCluster(ctx context.Context, name string) (cluster.Cluster, error) {
+	// ecs:DescribeClusters
+	resultDescribeClusters, err := p.svc.DescribeClustersWithContext(ctx, &ecs.DescribeClustersInput{
+		Clusters: []*string{aws.String(name)},
+		Include:  aws.StringSlice([]string{ecs.ClusterFieldTags}),
+	})
+	if err != nil {
+		return cluster.Cluster{}, newError("ecs", name, "ecs:DescribeClusters", err)
+	}
+
+	ecsClusters := resultDescribeClusters.Clusters
+	if len(ecsClusters) == 0 {
+		return cluster.Cluster{}, notFound("ecs", name, "ecs:DescribeClusters", "cluster %s not found", name)
+	}
+
+	return normalizeEcsCluster(ecsClusters[0]), nil
+}
+
+// ListNodes lists and describes the container instances of an ECS cluster.
+// Nodes whose EC2 instance can't be described are listed without their
+// private IP and the error is returned with them.
+func (p *ECS) ListNodes(ctx context.Context, c cluster.Cluster) ([]node.Node, error) {
+	var containerInstanceArns []*string
+	input := &ecs.ListContainerInstancesInput{
+		Cluster: aws.String(c.Arn),
+	}
+	for {
+		// ecs:ListContainerInstances
+		resultListContainerInstances, err := p.svc.ListContainerInstancesWithContext(ctx, input)
+		if err != nil {
+			return nil, newError("ecs", c.Name, "ecs:ListContainerInstances", err)
+		}
+
+		containerInstanceArns = append(containerInstanceArns, resultListContainerInstances.ContainerInstanceArns...)
+		if aws.StringValue(resultListContainerInstances.NextToken) == "" {
+			break
+		}
+		input.NextToken = resultListContainerInstances.NextToken
+	}
+
+	var errs Errors
+	nodes := make([]node.Node, 0, len(containerInstanceArns))
+	for _, arns := range batch(containerInstanceArns, ecsDescribeContainerInstancesLimit) {
+		// ecs:DescribeContainerInstances
+		resultDescribeContainerInstances, err := p.svc.DescribeContainerInstancesWithContext(ctx, &ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(c.Arn),
+			ContainerInstances: arns,
+		})
+		if err != nil {
+			return nil, newError("ecs", c.Name, "ecs:DescribeContainerInstances", err)
+		}
+
+		privateIPs, err := p.privateIPs(ctx, c, resultDescribeContainerInstances.ContainerInstances)
+		if err != nil {
+			errs = append(errs, record("ecs", c.Name, "ec2:DescribeInstances", err)...)
+		}
+
+		for _, ecsNode := range resultDescribeContainerInstances.ContainerInstances {
+			nodes = append(nodes, normalizeEcsNode(ecsNode, privateIPs, c))
+		}
+	}
+
+	return nodes, errs.orNil()
+}
+
+// DescribeNode describes a single container instance by ID or ARN
+func (p *ECS) DescribeNode(ctx context.Context, c cluster.Cluster, name string) (node.Node, error) {
+	// ecs:DescribeContainerInstances
+	resultDescribeContainerInstances, err := p.svc.DescribeContainerInstancesWithContext(ctx, &ecs.DescribeContainerInstancesInput{
+		Cluster:            aws.String(c.Arn),
+		ContainerInstances: []*string{aws.String(name)},
+	})
+	if err != nil {
+		return node.Node{}, newError("ecs", c.Name, "ecs:DescribeContainerInstances", err)
+	}
+
+	ecsNodes := resultDescribeContainerInstances.ContainerInstances
+	if len(ecsNodes) == 0 {
+		return node.Node{}, notFound("ecs", c.Name, "ecs:DescribeContainerInstances", "container instance %s not found", name)
+	}
+
+	// The node is still described without its private IP when EC2 can't be
+	// read; the error is logged
+	privateIPs, _ := p.privateIPs(ctx, c, ecsNodes)
+
+	return normalizeEcsNode(ecsNodes[0], privateIPs, c), nil
+}
+
+// privateIPs returns the private IP of the EC2 instance of each container
+// instance by instance ID, or nothing without an EC2 client. The IPs found
+// before a failed call are returned with its error.
+func (p *ECS) privateIPs(ctx context.Context, c cluster.Cluster, ecsNodes []*ecs.ContainerInstance) (map[string]string, error) {
+	privateIPs := map[string]string{}
+	if p.ec2 == nil || len(ecsNodes) == 0 {
+		return privateIPs, nil
+	}
+
+	instanceIds := make([]*string, len(ecsNodes))
+	for i, ecsNode := range ecsNodes {
+		instanceIds[i] = ecsNode.Ec2InstanceId
+	}
+
+	// ec2:DescribeInstances
+	err := p.ec2.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIds,
+	}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				privateIPs[aws.StringValue(instance.InstanceId)] = aws.StringValue(instance.PrivateIpAddress)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return privateIPs, newError("ecs", c.Name, "ec2:DescribeInstances", err)
+	}
+
+	return privateIPs, nil
+}
+
+// ListServices lists the services of an ECS cluster
+func (p *ECS) ListServices(ctx context.Context, c cluster.Cluster) ([]service.Service, error) {
+	ecsServices, err := p.services(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]service.Service, len(ecsServices))
+	for i, ecsService := range ecsServices {
+		services[i] = normalizeEcsService(ecsService, c)
+	}
+	return services, nil
+}
+
+// ListWorkloads lists the services of an ECS cluster as workloads, which
+// makes them comparable to Kubernetes workload controllers
+func (p *ECS) ListWorkloads(ctx context.Context, c cluster.Cluster) ([]workload.Workload, error) {
+	ecsServices, err := p.services(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	workloads := make([]workload.Workload, len(ecsServices))
+	for i, ecsService := range ecsServices {
+		workloads[i] = normalizeEcsWorkload(ecsService, c)
+	}
+	return workloads, nil
+}
+
+// services lists and describes the services of an ECS cluster
+func (p *ECS) services(ctx context.Context, c cluster.Cluster) ([]*ecs.Service, error) {
+	var serviceArns []*string
+	input := &ecs.ListServicesInput{
+		Cluster: aws.String(c.Arn),
+	}
+	for {
+		// ecs:ListServices
+		resultListServices, err := p.svc.ListServicesWithContext(ctx, input)
+		if err != nil {
+			return nil, newError("ecs", c.Name, "ecs:ListServices", err)
+		}
+
+		serviceArns = append(serviceArns, resultListServices.ServiceArns...)
+		if aws.StringValue(resultListServices.NextToken) == "" {
+			break
+		}
+		input.NextToken = resultListServices.NextToken
+	}
+
+	ecsServices := make([]*ecs.Service, 0, len(serviceArns))
+	for _, arns := range batch(serviceArns, ecsDescribeServicesLimit) {
+		// ecs:DescribeServices
+		resultDescribeServices, err := p.svc.DescribeServicesWithContext(ctx, &ecs.DescribeServicesInput{
+			Cluster:  aws.String(c.Arn),
+			Services: arns,
+		})
+		if err != nil {
+			return nil, newError("ecs", c.Name, "ecs:DescribeServices", err)
+		}
+
+		ecsServices = append(ecsServices, resultDescribeServices.Services...)
+	}
+
+	return ecsServices, nil
+}
+
+// DescribeService describes a single ECS service by name or ARN. ECS services
+// have no namespace, so namespace is ignored.
+func (p *ECS) DescribeService(ctx context.Context, c cluster.Cluster, namespace, name string) (service.Detail, error) {
+	// ecs:DescribeServices
+	resultDescribeServices, err := p.svc.DescribeServicesWithContext(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(c.Arn),
+		Services: []*string{aws.String(name)},
+	})
+	if err != nil {
+		return service.Detail{}, newError("ecs", c.Name, "ecs:DescribeServices", err)
+	}
+
+	ecsServices := resultDescribeServices.Services
+	if len(ecsServices) == 0 {
+		return service.Detail{}, notFound("ecs", c.Name, "ecs:DescribeServices", "service %s not found", name)
+	}
+
+	return normalizeEcsServiceDetail(ecsServices[0], c), nil
+}
+
+// ListTasks lists and describes the running tasks of an ECS cluster
+func (p *ECS) ListTasks(ctx context.Context, c cluster.Cluster) ([]task.Task, error) {
+	var taskArns []*string
+	input := &ecs.ListTasksInput{
+		Cluster: aws.String(c.Arn),
+	}
+	for {
+		// ecs:ListTasks
+		resultListTasks, err := p.svc.ListTasksWithContext(ctx, input)
+		if err != nil {
+			return nil, newError("ecs", c.Name, "ecs:ListTasks", err)
+		}
+
+		taskArns = append(taskArns, resultListTasks.TaskArns...)
+		if aws.StringValue(resultListTasks.NextToken) == "" {
+			break
+		}
+		input.NextToken = resultListTasks.NextToken
+	}
+
+	tasks := make([]task.Task, 0, len(taskArns))
+	for _, arns := range batch(taskArns, ecsDescribeTasksLimit) {
+		// ecs:DescribeTasks
+		resultDescribeTasks, err := p.svc.DescribeTasksWithContext(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(c.Arn),
+			Tasks:   arns,
+		})
+		if err != nil {
+			return nil, newError("ecs", c.Name, "ecs:DescribeTasks", err)
+		}
+
+		for _, ecsTask := range resultDescribeTasks.Tasks {
+			tasks = append(tasks, normalizeEcsTask(ecsTask, c))
+		}
+	}
+
+	return tasks, nil
+}
+
+// DescribeTask describes a single task by ID or ARN
+func (p *ECS) DescribeTask(ctx context.Context, c cluster.Cluster, id string) (task.Task, error) {
+	// ecs:DescribeTasks
+	resultDescribeTasks, err := p.svc.DescribeTasksWithContext(ctx, &ecs.DescribeTasksInput{
+		Cluster: aws.String(c.Arn),
+		Tasks:   []*string{aws.String(id)},
+	})
+	if err != nil {
+		return task.Task{}, newError("ecs", c.Name, "ecs:DescribeTasks", err)
+	}
+
+	ecsTasks := resultDescribeTasks.Tasks
+	if len(ecsTasks) == 0 {
+		return task.Task{}, notFound("ecs", c.Name, "ecs:DescribeTasks", "task %s not found", id)
+	}
+
+	return normalizeEcsTask(ecsTasks[0], c), nil
+}
+
+// arnLocation returns the region and account ID of an ARN
+func arnLocation(resourceArn string) (string, string) {
+	parsed, err := arn.Parse(resourceArn)
+	if err != nil {
+		return "", ""
+	}
+	return parsed.Region, parsed.AccountID
+}
+
+// batch splits identifiers into chunks no larger than size
+func batch(ids []*string, size int) [][]*string {
+	var batches [][]*string
+	for size < len(ids) {
+		ids, batches = ids[size:], append(batches, ids[:size])
+	}
+	if len(ids) > 0 {
+		batches = append(batches, ids)
+	}
+	return batches
+}
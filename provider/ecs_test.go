@@ -0,0 +1,462 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/buzzsurfr/harbormaster/upstream"
+	"github.com/stretchr/testify/assert"
+)
+
+// page returns the identifiers after the offset encoded in token, at most
+// size of them, and the token of the following page
+func page(ids []string, token *string, size int) ([]*string, *string) {
+	start, _ := strconv.Atoi(aws.StringValue(token))
+	end := start + size
+	if end >= len(ids) {
+		return aws.StringSlice(ids[start:]), nil
+	}
+	return aws.StringSlice(ids[start:end]), aws.String(strconv.Itoa(end))
+}
+
+// fakeECS paginates List calls and enforces the Describe batch limits of the
+// ECS API
+type fakeECS struct {
+	ecsiface.ECSAPI
+	pageSize           int
+	clusters           []string
+	containerInstances []string
+	services           []string
+	servicesErr        error
+	tasks              []string
+	describeCalls      int
+}
+
+func (f *fakeECS) ListClustersWithContext(ctx aws.Context, input *ecs.ListClustersInput, opts ...request.Option) (*ecs.ListClustersOutput, error) {
+	arns, next := page(f.clusters, input.NextToken, f.pageSize)
+	return &ecs.ListClustersOutput{ClusterArns: arns, NextToken: next}, nil
+}
+
+func (f *fakeECS) DescribeClustersWithContext(ctx aws.Context, input *ecs.DescribeClustersInput, opts ...request.Option) (*ecs.DescribeClustersOutput, error) {
+	f.describeCalls++
+	if len(input.Clusters) > ecsDescribeClustersLimit {
+		return nil, awserr.New(ecs.ErrCodeInvalidParameterException, "too many clusters", nil)
+	}
+	output := &ecs.DescribeClustersOutput{}
+	for _, arn := range input.Clusters {
+		name := strings.TrimPrefix(aws.StringValue(arn), "arn:aws:ecs:us-east-1:123456789012:cluster/")
+		output.Clusters = append(output.Clusters, &ecs.Cluster{
+			ClusterArn:  arn,
+			ClusterName: aws.String(name),
+			Status:      aws.String("ACTIVE"),
+		})
+	}
+	return output, nil
+}
+
+func (f *fakeECS) ListContainerInstancesWithContext(ctx aws.Context, input *ecs.ListContainerInstancesInput, opts ...request.Option) (*ecs.ListContainerInstancesOutput, error) {
+	arns, next := page(f.containerInstances, input.NextToken, f.pageSize)
+	return &ecs.ListContainerInstancesOutput{ContainerInstanceArns: arns, NextToken: next}, nil
+}
+
+func (f *fakeECS) DescribeContainerInstancesWithContext(ctx aws.Context, input *ecs.DescribeContainerInstancesInput, opts ...request.Option) (*ecs.DescribeContainerInstancesOutput, error) {
+	f.describeCalls++
+	if len(input.ContainerInstances) > ecsDescribeContainerInstancesLimit {
+		return nil, awserr.New(ecs.ErrCodeInvalidParameterException, "too many container instances", nil)
+	}
+	output := &ecs.DescribeContainerInstancesOutput{}
+	for i, arn := range input.ContainerInstances {
+		output.ContainerInstances = append(output.ContainerInstances, &ecs.ContainerInstance{
+			ContainerInstanceArn: arn,
+			Ec2InstanceId:        aws.String(fmt.Sprintf("i-%08d", i)),
+			Status:               aws.String("ACTIVE"),
+			VersionInfo:          &ecs.VersionInfo{AgentVersion: aws.String("1.51.0")},
+			Attributes: []*ecs.Attribute{
+				{Name: aws.String("ecs.instance-type"), Value: aws.String("m5.large")},
+				{Name: aws.String("ecs.availability-zone"), Value: aws.String("us-east-1a")},
+				{Name: aws.String("ecs.os-type"), Value: aws.String("linux")},
+				{Name: aws.String("ecs.ami-id"), Value: aws.String("ami-0abcdef")},
+			},
+			RegisteredResources: []*ecs.Resource{
+				{Name: aws.String("CPU"), IntegerValue: aws.Int64(2048)},
+				{Name: aws.String("MEMORY"), IntegerValue: aws.Int64(7680)},
+			},
+			RemainingResources: []*ecs.Resource{
+				{Name: aws.String("CPU"), IntegerValue: aws.Int64(1024)},
+				{Name: aws.String("MEMORY"), IntegerValue: aws.Int64(3072)},
+			},
+		})
+	}
+	return output, nil
+}
+
+func (f *fakeECS) ListServicesWithContext(ctx aws.Context, input *ecs.ListServicesInput, opts ...request.Option) (*ecs.ListServicesOutput, error) {
+	if f.servicesErr != nil {
+		return nil, f.servicesErr
+	}
+	arns, next := page(f.services, input.NextToken, f.pageSize)
+	return &ecs.ListServicesOutput{ServiceArns: arns, NextToken: next}, nil
+}
+
+func (f *fakeECS) DescribeServicesWithContext(ctx aws.Context, input *ecs.DescribeServicesInput, opts ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	f.describeCalls++
+	if len(input.Services) > ecsDescribeServicesLimit {
+		return nil, awserr.New(ecs.ErrCodeInvalidParameterException, "too many services", nil)
+	}
+	output := &ecs.DescribeServicesOutput{}
+	for _, arn := range input.Services {
+		name := aws.StringValue(arn)
+		output.Services = append(output.Services, &ecs.Service{
+			ServiceArn:  arn,
+			ServiceName: aws.String(name[strings.LastIndex(name, "/")+1:]),
+			Status:      aws.String("ACTIVE"),
+			LaunchType:  aws.String("FARGATE"),
+		})
+	}
+	return output, nil
+}
+
+func (f *fakeECS) ListTasksWithContext(ctx aws.Context, input *ecs.ListTasksInput, opts ...request.Option) (*ecs.ListTasksOutput, error) {
+	arns, next := page(f.tasks, input.NextToken, f.pageSize)
+	return &ecs.ListTasksOutput{TaskArns: arns, NextToken: next}, nil
+}
+
+func (f *fakeECS) DescribeTasksWithContext(ctx aws.Context, input *ecs.DescribeTasksInput, opts ...request.Option) (*ecs.DescribeTasksOutput, error) {
+	f.describeCalls++
+	if len(input.Tasks) > ecsDescribeTasksLimit {
+		return nil, awserr.New(ecs.ErrCodeInvalidParameterException, "too many tasks", nil)
+	}
+	output := &ecs.DescribeTasksOutput{}
+	for _, arn := range input.Tasks {
+		output.Tasks = append(output.Tasks, &ecs.Task{
+			TaskArn:              arn,
+			ContainerInstanceArn: aws.String("arn:aws:ecs:us-east-1:123456789012:container-instance/0"),
+			Group:                aws.String("service:web"),
+			DesiredStatus:        aws.String("RUNNING"),
+			LastStatus:           aws.String("RUNNING"),
+			LaunchType:           aws.String("EC2"),
+			Containers: []*ecs.Container{
+				{
+					Name:              aws.String("app"),
+					Image:             aws.String("web:1.4.2"),
+					ImageDigest:       aws.String("sha256:3f9a"),
+					LastStatus:        aws.String("RUNNING"),
+					HealthStatus:      aws.String("HEALTHY"),
+					Cpu:               aws.String("512"),
+					MemoryReservation: aws.String("128"),
+					Memory:            aws.String("256"),
+				},
+			},
+		})
+	}
+	return output, nil
+}
+
+func arns(format string, n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf(format, i)
+	}
+	return ids
+}
+
+func TestECSListClustersPaginates(t *testing.T) {
+	fake := &fakeECS{pageSize: 100, clusters: arns("arn:aws:ecs:us-east-1:123456789012:cluster/cluster-%03d", 250)}
+
+	clusters, err := NewECS(fake).ListClusters(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, clusters, 250)
+	assert.Equal(t, "cluster-000", clusters[0].Name)
+	assert.Equal(t, "cluster-249", clusters[249].Name)
+	assert.Equal(t, 3, fake.describeCalls)
+}
+
+func TestECSListNodesPaginates(t *testing.T) {
+	fake := &fakeECS{pageSize: 100, containerInstances: arns("arn:aws:ecs:us-east-1:123456789012:container-instance/%d", 201)}
+	c := cluster.Cluster{Name: "default", Arn: "arn:aws:ecs:us-east-1:123456789012:cluster/default", Scheduler: "ecs"}
+
+	nodes, err := NewECS(fake).ListNodes(context.Background(), c)
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 201)
+	assert.Equal(t, "200", nodes[200].Name)
+	assert.Equal(t, 3, fake.describeCalls)
+}
+
+func TestECSListServicesBatchesDescribe(t *testing.T) {
+	fake := &fakeECS{pageSize: 10, services: arns("arn:aws:ecs:us-east-1:123456789012:service/service-%03d", 95)}
+	c := cluster.Cluster{Name: "default", Arn: "arn:aws:ecs:us-east-1:123456789012:cluster/default", Scheduler: "ecs"}
+
+	services, err := NewECS(fake).ListServices(context.Background(), c)
+	assert.Nil(t, err)
+	assert.Len(t, services, 95)
+	assert.Equal(t, "service-094", services[94].Name)
+	assert.Equal(t, 10, fake.describeCalls)
+}
+
+func TestECSListTasks(t *testing.T) {
+	fake := &fakeECS{pageSize: 100, tasks: arns("arn:aws:ecs:us-east-1:123456789012:task/%d", 150)}
+	c := cluster.Cluster{Name: "default", Arn: "arn:aws:ecs:us-east-1:123456789012:cluster/default", Scheduler: "ecs"}
+
+	tasks, err := NewECS(fake).ListTasks(context.Background(), c)
+	assert.Nil(t, err)
+	assert.Len(t, tasks, 150)
+	assert.Equal(t, 2, fake.describeCalls)
+	assert.Equal(t, task.Task{
+		ID:            "149",
+		Arn:           "arn:aws:ecs:us-east-1:123456789012:task/149",
+		Scheduler:     "ecs",
+		Cluster:       c,
+		Node:          "0",
+		Service:       "web",
+		DesiredStatus: "RUNNING",
+		LastStatus:    "RUNNING",
+		LaunchType:    "ec2",
+		Containers: []task.Container{{
+			Name:         "app",
+			Image:        "web:1.4.2",
+			ImageDigest:  "sha256:3f9a",
+			Status:       "RUNNING",
+			Health:       "HEALTHY",
+			Reservations: task.Resources{CPU: 500, Memory: 128},
+			Limits:       task.Resources{Memory: 256},
+		}},
+	}, tasks[149])
+}
+
+func TestECSListClustersEmpty(t *testing.T) {
+	fake := &fakeECS{pageSize: 100}
+
+	clusters, err := NewECS(fake).ListClusters(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []cluster.Cluster{}, clusters)
+	assert.Equal(t, 0, fake.describeCalls)
+}
+
+func TestBatch(t *testing.T) {
+	ids := aws.StringSlice(arns("%d", 25))
+
+	batches := batch(ids, 10)
+	assert.Len(t, batches, 3)
+	assert.Len(t, batches[0], 10)
+	assert.Len(t, batches[2], 5)
+	assert.Nil(t, batch(nil, 10))
+}
+
+func TestECSListServicesReportsError(t *testing.T) {
+	fake := &fakeECS{
+		pageSize:    100,
+		clusters:    []string{"arn:aws:ecs:us-east-1:123456789012:cluster/default"},
+		servicesErr: awserr.New(ecs.ErrCodeAccessDeniedException, "not authorized to perform ecs:ListServices", nil),
+	}
+
+	services, errs := Set{NewECS(fake)}.ListServices(context.Background())
+	assert.Equal(t, []service.Service{}, services)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, &Error{
+			Scheduler: "ecs",
+			Cluster:   "default",
+			Operation: "ecs:ListServices",
+			Kind:      upstream.AccessDenied,
+			Code:      ecs.ErrCodeAccessDeniedException,
+			Message:   "not authorized to perform ecs:ListServices",
+			Err:       fake.servicesErr,
+		}, errs[0])
+	}
+}
+
+func TestNormalizeEcsServiceDetail(t *testing.T) {
+	c := cluster.Cluster{Name: "default", Arn: "arn:aws:ecs:us-east-1:123456789012:cluster/default", Scheduler: "ecs"}
+	detail := normalizeEcsServiceDetail(&ecs.Service{
+		ServiceArn:     aws.String("arn:aws:ecs:us-east-1:123456789012:service/default/web"),
+		ServiceName:    aws.String("web"),
+		Status:         aws.String("ACTIVE"),
+		LaunchType:     aws.String("FARGATE"),
+		DesiredCount:   aws.Int64(2),
+		RunningCount:   aws.Int64(1),
+		PendingCount:   aws.Int64(1),
+		TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/web:7"),
+		LoadBalancers: []*ecs.LoadBalancer{{
+			TargetGroupArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/web/73e2d6bc24d8a067"),
+			ContainerName:  aws.String("app"),
+			ContainerPort:  aws.Int64(8080),
+		}},
+		ServiceRegistries: []*ecs.ServiceRegistry{{ContainerPort: aws.Int64(8080)}, {Port: aws.Int64(9090)}},
+		DeploymentConfiguration: &ecs.DeploymentConfiguration{
+			MaximumPercent:           aws.Int64(200),
+			MinimumHealthyPercent:    aws.Int64(100),
+			DeploymentCircuitBreaker: &ecs.DeploymentCircuitBreaker{Enable: aws.Bool(true), Rollback: aws.Bool(true)},
+		},
+		DeploymentController: &ecs.DeploymentController{Type: aws.String("ECS")},
+		Deployments: []*ecs.Deployment{{
+			Id:             aws.String("ecs-svc/1234"),
+			Status:         aws.String("PRIMARY"),
+			TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/web:7"),
+			DesiredCount:   aws.Int64(2),
+			RunningCount:   aws.Int64(1),
+			PendingCount:   aws.Int64(1),
+			RolloutState:   aws.String("IN_PROGRESS"),
+		}},
+	}, c)
+
+	assert.Equal(t, "web", detail.Name)
+	assert.Equal(t, int64(2), detail.DesiredCount)
+	assert.Equal(t, "arn:aws:ecs:us-east-1:123456789012:task-definition/web:7", detail.TaskDefinition)
+	assert.Equal(t, []service.Port{
+		{Protocol: "TCP", Port: 8080, TargetPort: "8080"},
+		{Protocol: "TCP", Port: 9090, TargetPort: "9090"},
+	}, detail.Ports)
+	assert.Equal(t, []service.LoadBalancer{{
+		TargetGroupArn: "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/web/73e2d6bc24d8a067",
+		ContainerName:  "app",
+		ContainerPort:  8080,
+	}}, detail.LoadBalancers)
+	assert.Equal(t, service.DeploymentConfiguration{
+		Strategy:              "ECS",
+		MaximumPercent:        200,
+		MinimumHealthyPercent: 100,
+		CircuitBreaker:        true,
+		Rollback:              true,
+	}, detail.DeploymentConfiguration)
+	if assert.Len(t, detail.Deployments, 1) {
+		assert.Equal(t, "PRIMARY", detail.Deployments[0].Status)
+		assert.Equal(t, "IN_PROGRESS", detail.Deployments[0].RolloutState)
+	}
+}
+
+func TestNormalizeEcsCluster(t *testing.T) {
+	c := normalizeEcsCluster(&ecs.Cluster{
+		ClusterName:                       aws.String("default"),
+		ClusterArn:                        aws.String("arn:aws:ecs:eu-west-1:123456789012:cluster/default"),
+		Status:                            aws.String("ACTIVE"),
+		RegisteredContainerInstancesCount: aws.Int64(3),
+		RunningTasksCount:                 aws.Int64(12),
+		PendingTasksCount:                 aws.Int64(1),
+		ActiveServicesCount:               aws.Int64(4),
+		Tags:                              []*ecs.Tag{{Key: aws.String("team"), Value: aws.String("payments")}},
+	})
+
+	assert.Equal(t, cluster.Cluster{
+		Name:            "default",
+		Arn:             "arn:aws:ecs:eu-west-1:123456789012:cluster/default",
+		Scheduler:       "ecs",
+		Status:          "ACTIVE",
+		Region:          "eu-west-1",
+		AccountID:       "123456789012",
+		Tags:            map[string]string{"team": "payments"},
+		RegisteredNodes: aws.Int64(3),
+		RunningTasks:    aws.Int64(12),
+		PendingTasks:    aws.Int64(1),
+		ActiveServices:  aws.Int64(4),
+	}, c)
+}
+
+// fakeEC2 reports a private IP for every instance it is asked about, unless
+// it is given an error to fail with
+type fakeEC2 struct {
+	ec2iface.EC2API
+	calls int
+	err   error
+}
+
+func (f *fakeEC2) DescribeInstancesPagesWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool, opts ...request.Option) error {
+	if f.err != nil {
+		return f.err
+	}
+	reservation := &ec2.Reservation{}
+	for i, id := range input.InstanceIds {
+		reservation.Instances = append(reservation.Instances, &ec2.Instance{
+			InstanceId:       id,
+			PrivateIpAddress: aws.String(fmt.Sprintf("10.0.1.%d", i+1)),
+		})
+	}
+	fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{reservation}}, true)
+	return nil
+}
+
+// DescribeInstancesWithContext knows a single instance, with the private DNS
+// name of an EKS node, and counts the calls made to it
+func (f *fakeEC2) DescribeInstancesWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, opts ...request.Option) (*ec2.DescribeInstancesOutput, error) {
+	f.calls++
+	for _, id := range input.InstanceIds {
+		if aws.StringValue(id) != "i-0123456789abcdef0" {
+			return nil, awserr.New("InvalidInstanceID.NotFound", "The instance ID does not exist", nil)
+		}
+	}
+	return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{
+		InstanceId:     aws.String("i-0123456789abcdef0"),
+		PrivateDnsName: aws.String("ip-10-0-1-23.eu-west-1.compute.internal"),
+	}}}}}, nil
+}
+
+func TestECSDescribeNode(t *testing.T) {
+	c := cluster.Cluster{Name: "default", Arn: "arn:aws:ecs:us-east-1:123456789012:cluster/default", Scheduler: "ecs"}
+
+	n, err := NewECS(&fakeECS{}).WithEC2(&fakeEC2{}).DescribeNode(context.Background(), c, "arn:aws:ecs:us-east-1:123456789012:container-instance/default/abc")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", n.Name)
+	assert.Equal(t, "10.0.1.1", n.PrivateIP)
+	assert.Equal(t, "m5.large", n.InstanceType)
+	assert.Equal(t, "us-east-1a", n.AvailabilityZone)
+	assert.Equal(t, "linux", n.OS)
+	assert.Equal(t, "ami-0abcdef", n.AMI)
+	assert.Equal(t, "1.51.0", n.AgentVersion)
+	assert.Equal(t, node.Resources{CPU: 2000, Memory: 7680}, n.Registered)
+	assert.Equal(t, node.Resources{CPU: 1000, Memory: 3072}, n.Remaining)
+}
+
+func TestECSListNodesWithoutPrivateIPs(t *testing.T) {
+	fake := &fakeECS{pageSize: 100, containerInstances: arns("arn:aws:ecs:us-east-1:123456789012:container-instance/%d", 3)}
+	c := cluster.Cluster{Name: "default", Arn: "arn:aws:ecs:us-east-1:123456789012:cluster/default", Scheduler: "ecs"}
+	ec2 := &fakeEC2{err: awserr.New("UnauthorizedOperation", "not authorized", nil)}
+
+	// Nodes are listed without their private IP and the failure is reported
+	nodes, err := NewECS(fake).WithEC2(ec2).ListNodes(context.Background(), c)
+	if assert.Len(t, nodes, 3) {
+		assert.Empty(t, nodes[0].PrivateIP)
+	}
+	if assert.IsType(t, Errors{}, err) && assert.Len(t, err.(Errors), 1) {
+		assert.Equal(t, "ec2:DescribeInstances", err.(Errors)[0].Operation)
+	}
+
+	n, err := NewECS(fake).WithEC2(ec2).DescribeNode(context.Background(), c, "arn:aws:ecs:us-east-1:123456789012:container-instance/default/abc")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", n.Name)
+	assert.Empty(t, n.PrivateIP)
+}
+
+func TestNormalizeEcsWorkload(t *testing.T) {
+	c := cluster.Cluster{Name: "default", Arn: "arn:aws:ecs:us-east-1:123456789012:cluster/default", Scheduler: "ecs"}
+	w := normalizeEcsWorkload(&ecs.Service{
+		ServiceArn:     aws.String("arn:aws:ecs:us-east-1:123456789012:service/default/web"),
+		ServiceName:    aws.String("web"),
+		Status:         aws.String("ACTIVE"),
+		DesiredCount:   aws.Int64(3),
+		RunningCount:   aws.Int64(3),
+		TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/web:8"),
+		Deployments: []*ecs.Deployment{
+			{Status: aws.String("PRIMARY"), RunningCount: aws.Int64(1)},
+			{Status: aws.String("ACTIVE"), RunningCount: aws.Int64(2)},
+		},
+	}, c)
+
+	assert.Equal(t, "Service", w.Kind)
+	assert.Equal(t, "ACTIVE", w.Status)
+	assert.Equal(t, int64(3), w.DesiredCount)
+	assert.Equal(t, int64(1), w.UpdatedCount)
+	assert.Equal(t, []string{"web"}, w.Services)
+	assert.Equal(t, "arn:aws:ecs:us-east-1:123456789012:task-definition/web:8", w.TaskDefinition)
+}
@@ -0,0 +1,1506 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/buzzsurfr/harbormaster/upstream"
+	"github.com/buzzsurfr/harbormaster/workload"
+	"github.com/kubernetes-sigs/aws-iam-authenticator/pkg/token"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// ClientsetFunc returns a Kubernetes client for an EKS cluster
+type ClientsetFunc func(eksCluster *eks.Cluster) (kubernetes.Interface, error)
+
+// EKS discovers clusters from Amazon EKS and nodes, services and pods from
+// the Kubernetes API of each cluster
+type EKS struct {
+	svc             eksiface.EKSAPI
+	ec2             ec2iface.EC2API
+	clientset       ClientsetFunc
+	namespaceFilter NamespaceFilter
+}
+
+// NewEKS returns a provider backed by an EKS client. Kubernetes clients are
+// authenticated with a token generated from the default AWS credentials.
+func NewEKS(svc eksiface.EKSAPI) *EKS {
+	return &EKS{
+		svc:             svc,
+		clientset:       NewClientset,
+		namespaceFilter: DefaultNamespaceFilter,
+	}
+}
+
+// WithClientset replaces the function used to build Kubernetes clients
+func (p *EKS) WithClientset(fn ClientsetFunc) *EKS {
+	p.clientset = fn
+	return p
+}
+
+// WithEC2 resolves node lookups by EC2 instance ID or private IP to the node
+// name with an EC2 client
+func (p *EKS) WithEC2(svc ec2iface.EC2API) *EKS {
+	p.ec2 = svc
+	return p
+}
+
+// Scheduler returns "eks"
+func (p *EKS) Scheduler() string {
+	return "eks"
+}
+
+// NewClientset returns a Kubernetes client for an EKS cluster, authenticated
+// with an aws-iam-authenticator token
+func NewClientset(eksCluster *eks.Cluster) (kubernetes.Interface, error) {
+	if eksCluster.CertificateAuthority == nil {
+		return nil, fmt.Errorf("cluster %s has no certificate authority", aws.StringValue(eksCluster.Name))
+	}
+
+	// Get Kubernetes token
+	gen, err := token.NewGenerator()
+	if err != nil {
+		return nil, err
+	}
+	tok, err := gen.Get(aws.StringValue(eksCluster.Name))
+	if err != nil {
+		return nil, err
+	}
+	certificateAuthorityData, err := base64.StdEncoding.DecodeString(aws.StringValue(eksCluster.CertificateAuthority.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetes.NewForConfig(&rest.Config{
+		Host:        aws.StringValue(eksCluster.Endpoint),
+		BearerToken: tok,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: certificateAuthorityData,
+		},
+	})
+}
+
+func normalizeEksCluster(eksCluster *eks.Cluster) cluster.Cluster {
+	currentCluster := cluster.Cluster{
+		Name:            *eksCluster.Name,
+		Arn:             *eksCluster.Arn,
+		Scheduler:       "eks",
+		Status:          *eksCluster.Status,
+		Version:         aws.StringValue(eksCluster.Version),
+		PlatformVersion: aws.StringValue(eksCluster.PlatformVersion),
+		Endpoint:        aws.StringValue(eksCluster.Endpoint),
+		CreatedAt:       eksCluster.CreatedAt,
+		Tags:            aws.StringValueMap(eksCluster.Tags),
+	}
+	currentCluster.Region, currentCluster.AccountID = arnLocation(currentCluster.Arn)
+	return currentCluster
+}
+
+func normalizeEksNode(eksNode *v1.Node, c cluster.Cluster) node.Node {
+	providerID := strings.Split(eksNode.Spec.ProviderID, "/")
+	status := "Unknown"
+	for i := range eksNode.Status.Conditions {
+		if eksNode.Status.Conditions[i].Type == "Ready" {
+			if eksNode.Status.Conditions[i].Status == "True" {
+				status = "Ready"
+			} else {
+				status = "NotReady"
+			}
+		}
+	}
+	currentNode := node.Node{
+		Name:             eksNode.Name,
+		UID:              string(eksNode.GetUID()),
+		Arn:              "",
+		InstanceID:       providerID[len(providerID)-1],
+		Scheduler:        "eks",
+		Status:           status,
+		InstanceType:     label(eksNode.Labels, "node.kubernetes.io/instance-type", "beta.kubernetes.io/instance-type"),
+		AvailabilityZone: label(eksNode.Labels, "topology.kubernetes.io/zone", "failure-domain.beta.kubernetes.io/zone"),
+		AgentVersion:     eksNode.Status.NodeInfo.KubeletVersion,
+		OS:               eksNode.Status.NodeInfo.OSImage,
+		AMI:              eksNode.Labels["eks.amazonaws.com/nodegroup-image"],
+		Labels:           eksNode.Labels,
+		Registered:       eksNodeResources(eksNode.Status.Capacity),
+		Remaining:        eksNodeResources(eksNode.Status.Allocatable),
+		Cluster:          c.Reference(),
+	}
+
+	for _, address := range eksNode.Status.Addresses {
+		if address.Type == v1.NodeInternalIP {
+			currentNode.PrivateIP = address.Address
+			break
+		}
+	}
+
+	return currentNode
+}
+
+// label returns the first of several labels that is set, so that both the
+// current and the deprecated names of well-known labels are found
+func label(nodeLabels map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := nodeLabels[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// eksNodeResources converts Kubernetes node resources to millicores and MiB
+func eksNodeResources(list v1.ResourceList) node.Resources {
+	resources := eksResources(list)
+	return node.Resources{CPU: resources.CPU, Memory: resources.Memory}
+}
+
+func normalizeEksService(eksService v1.Service, eksEndpoints *v1.Endpoints, controllers []eksController, c cluster.Cluster) service.Service {
+	currentService := service.Service{
+		Name:          eksService.Name,
+		Arn:           "",
+		Status:        eksServiceStatus(eksService, eksEndpoints, controllers),
+		Cluster:       c.Reference(),
+		Scheduler:     "eks",
+		Namespace:     eksService.Namespace,
+		Type:          string(eksService.Spec.Type),
+		Selector:      eksService.Spec.Selector,
+		Ports:         make([]service.Port, len(eksService.Spec.Ports)),
+		LoadBalancers: make([]service.LoadBalancer, len(eksService.Status.LoadBalancer.Ingress)),
+	}
+
+	// Headless services have a cluster IP of "None"
+	if clusterIP := eksService.Spec.ClusterIP; clusterIP != "" && clusterIP != v1.ClusterIPNone {
+		currentService.ClusterIPs = []string{clusterIP}
+	}
+
+	for i, eksPort := range eksService.Spec.Ports {
+		currentService.Ports[i] = service.Port{
+			Name:       eksPort.Name,
+			Protocol:   string(eksPort.Protocol),
+			Port:       int64(eksPort.Port),
+			TargetPort: eksPort.TargetPort.String(),
+			NodePort:   int64(eksPort.NodePort),
+		}
+	}
+
+	for i, ingress := range eksService.Status.LoadBalancer.Ingress {
+		currentService.LoadBalancers[i] = service.LoadBalancer{
+			Hostname: ingress.Hostname,
+			IP:       ingress.IP,
+		}
+	}
+
+	// Controllers are listed by kind and name, so the first one names the
+	// template
+	for i, controller := range eksSelected(eksService, controllers) {
+		if i == 0 {
+			currentService.TaskDefinition = controller.TaskDefinition
+		}
+		currentService.DesiredCount += controller.DesiredCount
+		currentService.RunningCount += controller.RunningCount
+		currentService.PendingCount += controller.PendingCount
+	}
+
+	return currentService
+}
+
+// eksController is a Deployment, StatefulSet or DaemonSet normalized as a
+// workload, along with the labels of the pods it runs so that services can
+// be matched to it
+type eksController struct {
+	workload.Workload
+	podLabels map[string]string
+}
+
+// eksControllers normalizes the workload controllers that run the pods of
+// services: Deployments, then StatefulSets, then DaemonSets
+func eksControllers(eksDeployments []appsv1.Deployment, eksStatefulSets []appsv1.StatefulSet, eksDaemonSets []appsv1.DaemonSet, c cluster.Cluster) []eksController {
+	var controllers []eksController
+	for _, eksDeployment := range eksDeployments {
+		controllers = append(controllers, eksController{normalizeEksDeploymentWorkload(eksDeployment, nil, c), eksDeployment.Spec.Template.Labels})
+	}
+	for _, eksStatefulSet := range eksStatefulSets {
+		controllers = append(controllers, eksController{normalizeEksStatefulSet(eksStatefulSet, nil, c), eksStatefulSet.Spec.Template.Labels})
+	}
+	for _, eksDaemonSet := range eksDaemonSets {
+		controllers = append(controllers, eksController{normalizeEksDaemonSet(eksDaemonSet, nil, c), eksDaemonSet.Spec.Template.Labels})
+	}
+	return controllers
+}
+
+// eksSelected returns the workload controllers whose pods a service selects
+func eksSelected(eksService v1.Service, controllers []eksController) []eksController {
+	if len(eksService.Spec.Selector) == 0 {
+		return nil
+	}
+
+	var selected []eksController
+	selector := labels.SelectorFromSet(eksService.Spec.Selector)
+	for _, controller := range controllers {
+		if controller.Namespace != eksService.Namespace || !selector.Matches(labels.Set(controller.podLabels)) {
+			continue
+		}
+		selected = append(selected, controller)
+	}
+	return selected
+}
+
+// eksServiceStatus maps the endpoints of a Kubernetes service and the ready
+// replicas of the workload controllers it selects onto the ECS service
+// statuses: ACTIVE when every endpoint and replica is ready, DEGRADED when
+// some aren't, DRAINING while the service is deleted or scaled to zero and
+// INACTIVE once nothing is left to serve it
+func eksServiceStatus(eksService v1.Service, eksEndpoints *v1.Endpoints, controllers []eksController) string {
+	if eksService.DeletionTimestamp != nil {
+		return "DRAINING"
+	}
+	if eksService.Spec.Type == v1.ServiceTypeExternalName {
+		return "ACTIVE"
+	}
+
+	var ready, notReady int
+	if eksEndpoints != nil {
+		for _, subset := range eksEndpoints.Subsets {
+			ready += len(subset.Addresses)
+			notReady += len(subset.NotReadyAddresses)
+		}
+	}
+
+	selectedControllers := eksSelected(eksService, controllers)
+	selected := len(selectedControllers) > 0
+	var desiredReplicas, readyReplicas int64
+	for _, controller := range selectedControllers {
+		desiredReplicas += controller.DesiredCount
+		readyReplicas += controller.RunningCount
+	}
+
+	switch {
+	case selected && desiredReplicas == 0 && ready+notReady > 0:
+		return "DRAINING"
+	case ready+notReady == 0 && (!selected || desiredReplicas == 0):
+		return "INACTIVE"
+	case ready == 0, notReady > 0, selected && readyReplicas < desiredReplicas:
+		return "DEGRADED"
+	default:
+		return "ACTIVE"
+	}
+}
+
+// eksWorkload returns the fields shared by every Kubernetes workload
+// controller. Deployments name their template by rollout revision and other
+// controllers by generation.
+func eksWorkload(kind string, meta metav1.ObjectMeta, revision string, podLabels map[string]string, eksServices []v1.Service, c cluster.Cluster) workload.Workload {
+	createdAt := meta.CreationTimestamp.Time
+	return workload.Workload{
+		Name:           meta.Name,
+		Kind:           kind,
+		Cluster:        c.Reference(),
+		Scheduler:      "eks",
+		Namespace:      meta.Namespace,
+		TaskDefinition: kind + "/" + meta.Name + ":" + revision,
+		Services:       eksSelecting(eksServices, meta.Namespace, podLabels),
+		CreatedAt:      &createdAt,
+	}
+}
+
+// eksSelecting returns the names of the services that select pods with the
+// given namespace and labels
+func eksSelecting(eksServices []v1.Service, namespace string, podLabels map[string]string) []string {
+	names := []string{}
+	for _, eksService := range eksServices {
+		if eksService.Namespace != namespace || len(eksService.Spec.Selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(eksService.Spec.Selector).Matches(labels.Set(podLabels)) {
+			names = append(names, eksService.Name)
+		}
+	}
+	return names
+}
+
+// eksWorkloadStatus maps the replicas of a workload controller onto the ECS
+// service statuses
+func eksWorkloadStatus(meta metav1.ObjectMeta, desired, ready int64) string {
+	switch {
+	case meta.DeletionTimestamp != nil:
+		return "DRAINING"
+	case desired == 0:
+		return "INACTIVE"
+	case ready < desired:
+		return "DEGRADED"
+	default:
+		return "ACTIVE"
+	}
+}
+
+func normalizeEksDeploymentWorkload(eksDeployment appsv1.Deployment, eksServices []v1.Service, c cluster.Cluster) workload.Workload {
+	currentWorkload := eksWorkload("Deployment", eksDeployment.ObjectMeta, eksDeployment.Annotations[eksRevision], eksDeployment.Spec.Template.Labels, eksServices, c)
+	if eksDeployment.Spec.Replicas != nil {
+		currentWorkload.DesiredCount = int64(*eksDeployment.Spec.Replicas)
+	}
+	currentWorkload.RunningCount = int64(eksDeployment.Status.ReadyReplicas)
+	currentWorkload.PendingCount = pending(eksDeployment.Status.Replicas, eksDeployment.Status.ReadyReplicas)
+	currentWorkload.UpdatedCount = int64(eksDeployment.Status.UpdatedReplicas)
+	currentWorkload.Status = eksWorkloadStatus(eksDeployment.ObjectMeta, currentWorkload.DesiredCount, currentWorkload.RunningCount)
+	return currentWorkload
+}
+
+func normalizeEksStatefulSet(eksStatefulSet appsv1.StatefulSet, eksServices []v1.Service, c cluster.Cluster) workload.Workload {
+	currentWorkload := eksWorkload("StatefulSet", eksStatefulSet.ObjectMeta, strconv.FormatInt(eksStatefulSet.Generation, 10), eksStatefulSet.Spec.Template.Labels, eksServices, c)
+	if eksStatefulSet.Spec.Replicas != nil {
+		currentWorkload.DesiredCount = int64(*eksStatefulSet.Spec.Replicas)
+	}
+	currentWorkload.RunningCount = int64(eksStatefulSet.Status.ReadyReplicas)
+	currentWorkload.PendingCount = pending(eksStatefulSet.Status.Replicas, eksStatefulSet.Status.ReadyReplicas)
+	currentWorkload.UpdatedCount = int64(eksStatefulSet.Status.UpdatedReplicas)
+	currentWorkload.Status = eksWorkloadStatus(eksStatefulSet.ObjectMeta, currentWorkload.DesiredCount, currentWorkload.RunningCount)
+	return currentWorkload
+}
+
+func normalizeEksDaemonSet(eksDaemonSet appsv1.DaemonSet, eksServices []v1.Service, c cluster.Cluster) workload.Workload {
+	currentWorkload := eksWorkload("DaemonSet", eksDaemonSet.ObjectMeta, strconv.FormatInt(eksDaemonSet.Generation, 10), eksDaemonSet.Spec.Template.Labels, eksServices, c)
+	currentWorkload.DesiredCount = int64(eksDaemonSet.Status.DesiredNumberScheduled)
+	currentWorkload.RunningCount = int64(eksDaemonSet.Status.NumberReady)
+	currentWorkload.PendingCount = pending(eksDaemonSet.Status.CurrentNumberScheduled, eksDaemonSet.Status.NumberReady)
+	currentWorkload.UpdatedCount = int64(eksDaemonSet.Status.UpdatedNumberScheduled)
+	currentWorkload.Status = eksWorkloadStatus(eksDaemonSet.ObjectMeta, currentWorkload.DesiredCount, currentWorkload.RunningCount)
+	return currentWorkload
+}
+
+// normalizeEksJob counts the completions a Job wants and the pods it has
+// active. Finished Jobs are COMPLETED or FAILED.
+func normalizeEksJob(eksJob batchv1.Job, eksServices []v1.Service, c cluster.Cluster) workload.Workload {
+	currentWorkload := eksWorkload("Job", eksJob.ObjectMeta, strconv.FormatInt(eksJob.Generation, 10), eksJob.Spec.Template.Labels, eksServices, c)
+	currentWorkload.DesiredCount = 1
+	if eksJob.Spec.Completions != nil {
+		currentWorkload.DesiredCount = int64(*eksJob.Spec.Completions)
+	}
+	currentWorkload.RunningCount = int64(eksJob.Status.Active)
+	currentWorkload.UpdatedCount = int64(eksJob.Status.Succeeded)
+	currentWorkload.Status = "ACTIVE"
+	for _, eksCondition := range eksJob.Status.Conditions {
+		if eksCondition.Status != v1.ConditionTrue {
+			continue
+		}
+		switch eksCondition.Type {
+		case batchv1.JobComplete:
+			currentWorkload.Status = "COMPLETED"
+		case batchv1.JobFailed:
+			currentWorkload.Status = "FAILED"
+		}
+	}
+	if eksJob.DeletionTimestamp != nil {
+		currentWorkload.Status = "DRAINING"
+	}
+	return currentWorkload
+}
+
+// normalizeEksCronJob counts the Jobs a CronJob has running. Suspended
+// CronJobs are INACTIVE.
+func normalizeEksCronJob(eksCronJob batchv1beta1.CronJob, eksServices []v1.Service, c cluster.Cluster) workload.Workload {
+	currentWorkload := eksWorkload("CronJob", eksCronJob.ObjectMeta, strconv.FormatInt(eksCronJob.Generation, 10), eksCronJob.Spec.JobTemplate.Spec.Template.Labels, eksServices, c)
+	currentWorkload.RunningCount = int64(len(eksCronJob.Status.Active))
+	currentWorkload.Status = "ACTIVE"
+	switch {
+	case eksCronJob.DeletionTimestamp != nil:
+		currentWorkload.Status = "DRAINING"
+	case eksCronJob.Spec.Suspend != nil && *eksCronJob.Spec.Suspend:
+		currentWorkload.Status = "INACTIVE"
+	}
+	return currentWorkload
+}
+
+func normalizeEksPod(eksPod *v1.Pod, eksNodes map[string]*v1.Node, c cluster.Cluster) task.Task {
+	// Pods being deleted are no longer meant to run
+	desiredStatus := "Running"
+	if eksPod.DeletionTimestamp != nil {
+		desiredStatus = "Stopped"
+	}
+
+	currentTask := task.Task{
+		ID:            eksPod.Namespace + "/" + eksPod.Name,
+		Name:          eksPod.Name,
+		Scheduler:     "eks",
+		Cluster:       c.Reference(),
+		Namespace:     eksPod.Namespace,
+		Node:          eksPod.Spec.NodeName,
+		DesiredStatus: desiredStatus,
+		LastStatus:    string(eksPod.Status.Phase),
+		Containers:    make([]task.Container, len(eksPod.Spec.Containers)),
+	}
+	currentTask.LaunchType, currentTask.CapacityProvider = eksPodLaunchType(eksPod, eksNodes)
+
+	if eksPod.Status.StartTime != nil {
+		startedAt := eksPod.Status.StartTime.Time
+		currentTask.StartedAt = &startedAt
+	}
+
+	if len(eksPod.OwnerReferences) > 0 {
+		currentTask.Owner = eksPod.OwnerReferences[0].Kind + "/" + eksPod.OwnerReferences[0].Name
+	}
+
+	for i, eksContainer := range eksPod.Spec.Containers {
+		currentTask.Containers[i] = normalizeEksContainer(eksContainer, eksPod.Status.ContainerStatuses)
+	}
+
+	return currentTask
+}
+
+func normalizeEksContainer(eksContainer v1.Container, statuses []v1.ContainerStatus) task.Container {
+	currentContainer := task.Container{
+		Name:         eksContainer.Name,
+		Image:        eksContainer.Image,
+		Status:       "Waiting",
+		Health:       "UNKNOWN",
+		Reservations: eksResources(eksContainer.Resources.Requests),
+		Limits:       eksResources(eksContainer.Resources.Limits),
+	}
+
+	for _, containerStatus := range statuses {
+		if containerStatus.Name != eksContainer.Name {
+			continue
+		}
+
+		// ImageID is the resolved image, e.g. docker-pullable://repo@sha256:...
+		if at := strings.LastIndex(containerStatus.ImageID, "@"); at >= 0 {
+			currentContainer.ImageDigest = containerStatus.ImageID[at+1:]
+		}
+		currentContainer.RestartCount = int64(containerStatus.RestartCount)
+
+		switch {
+		case containerStatus.State.Running != nil:
+			currentContainer.Status = "Running"
+		case containerStatus.State.Terminated != nil:
+			currentContainer.Status = "Terminated"
+			exitCode := int64(containerStatus.State.Terminated.ExitCode)
+			currentContainer.ExitCode = &exitCode
+		}
+
+		// Readiness stands in for the health check of ECS containers
+		switch {
+		case containerStatus.Ready:
+			currentContainer.Health = "HEALTHY"
+		case containerStatus.State.Running != nil:
+			currentContainer.Health = "UNHEALTHY"
+		}
+	}
+
+	return currentContainer
+}
+
+// eksResources converts Kubernetes resource quantities to millicores and MiB
+func eksResources(list v1.ResourceList) task.Resources {
+	var resources task.Resources
+	if cpu, ok := list[v1.ResourceCPU]; ok {
+		resources.CPU = cpu.MilliValue()
+	}
+	if memory, ok := list[v1.ResourceMemory]; ok {
+		resources.Memory = memory.Value() / (1 << 20)
+	}
+	return resources
+}
+
+// describeCluster returns the raw EKS cluster, which carries the endpoint and
+// certificate authority needed to reach the Kubernetes API
+func (p *EKS) describeCluster(ctx context.Context, name string) (*eks.Cluster, error) {
+	// eks:DescribeCluster
+	resultDescribeCluster, err := p.svc.DescribeClusterWithContext(ctx, &eks.DescribeClusterInput{
+		Name: aws.String(name),
+	})
+	if err != nil {
+		return nil, newError("eks", name, "eks:DescribeCluster", err)
+	}
+
+	return resultDescribeCluster.Cluster, nil
+}
+
+// kubeClient returns a Kubernetes client for a normalized EKS cluster
+func (p *EKS) kubeClient(ctx context.Context, c cluster.Cluster) (kubernetes.Interface, error) {
+	eksCluster, err := p.describeCluster(ctx, c.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := p.clientset(eksCluster)
+	if err != nil {
+		return nil, newError("eks", c.Name, "kubernetes:Connect", err)
+	}
+
+	return clientset, nil
+}
+
+// ListClusters lists and describes all EKS clusters. Clusters that can't be
+// described are left out and reported in Errors.
+func (p *EKS) ListClusters(ctx context.Context) ([]cluster.Cluster, error) {
+	var clusterNames []*string
+	input := &eks.ListClustersInput{}
+	for {
+		// eks:ListClusters
+		resultListClusters, err := p.svc.ListClustersWithContext(ctx, input)
+		if err != nil {
+			return nil, newError("eks", "", "eks:ListClusters", err)
+		}
+
+		clusterNames = append(clusterNames, resultListClusters.Clusters...)
+		if aws.StringValue(resultListClusters.NextToken) == "" {
+			break
+		}
+		input.NextToken = resultListClusters.NextToken
+	}
+
+	// eks:DescribeCluster (per cluster)
+	described := make([]cluster.Cluster, len(clusterNames))
+	describeErrs := forEach(ctx, len(clusterNames), func(i int) error {
+		eksCluster, err := p.describeCluster(ctx, aws.StringValue(clusterNames[i]))
+		if err != nil {
+			return err
+		}
+
+		described[i] = normalizeEksCluster(eksCluster)
+		return nil
+	})
+
+	// Clusters that couldn't be described are reported one by one, so the
+	// others are still listed
+	var errs Errors
+	clusters := []cluster.Cluster{}
+	for i, err := range describeErrs {
+		if err != nil {
+			errs = append(errs, newError("eks", aws.StringValue(clusterNames[i]), "eks:DescribeCluster", err))
+			continue
+		}
+		clusters = append(clusters, described[i])
+	}
+
+	return clusters, errs.orNil()
+}
+
+// DescribeCluster describes a single EKS cluster by name
+func (p *EKS) DescribeCluster(ctx context.Context, name string) (cluster.Cluster, error) {
+	eksCluster, err := p.describeCluster(ctx, name)
+	if err != nil {
+		return cluster.Cluster{}, err
+	}
+
+	return normalizeEksCluster(eksCluster), nil
+}
+
+// CountCluster fills in the node, task and service counts of an active EKS
+// cluster from its Kubernetes API, since EKS doesn't report them
+func (p *EKS) CountCluster(ctx context.Context, c cluster.Cluster) (cluster.Cluster, error) {
+	if c.Status != eks.ClusterStatusActive {
+		return c, nil
+	}
+
+	clientset, err := p.kubeClient(ctx, c)
+	if err != nil {
+		return c, err
+	}
+
+	eksNodes, err := p.nodes(ctx, clientset, c)
+	if err != nil {
+		return c, err
+	}
+	eksPods, err := p.pods(ctx, clientset, c)
+	if err != nil {
+		return c, err
+	}
+	eksServices, err := p.services(ctx, clientset, c)
+	if err != nil {
+		return c, err
+	}
+
+	var running, pending int64
+	for _, eksPod := range eksPods {
+		switch eksPod.Status.Phase {
+		case v1.PodRunning:
+			running++
+		case v1.PodPending:
+			pending++
+		}
+	}
+	c.RegisteredNodes = aws.Int64(int64(len(eksNodes)))
+	c.RunningTasks = aws.Int64(running)
+	c.PendingTasks = aws.Int64(pending)
+	c.ActiveServices = aws.Int64(int64(len(eksServices)))
+	return c, nil
+}
+
+// ListNodes lists the Kubernetes nodes of an EKS cluster
+func (p *EKS) ListNodes(ctx context.Context, c cluster.Cluster) ([]node.Node, error) {
+	clientset, err := p.kubeClient(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	eksNodes, err := p.nodes(ctx, clientset, c)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]node.Node, len(eksNodes))
+	for i := range eksNodes {
+		nodes[i] = normalizeEksNode(&eksNodes[i], c)
+	}
+
+	return nodes, nil
+}
+
+// DescribeNode describes a single Kubernetes node by name, UID, EC2 instance
+// ID or private IP. Instance IDs and IPs are resolved to the node name, with
+// EC2 if available, so the node can be read directly. Nodes can't be read by
+// UID, so only identifiers shaped like a UID are found by listing every node.
+func (p *EKS) DescribeNode(ctx context.Context, c cluster.Cluster, name string) (node.Node, error) {
+	clientset, err := p.kubeClient(ctx, c)
+	if err != nil {
+		return node.Node{}, err
+	}
+
+	// getNode gets a node by name and reports whether it matches name
+	getNode := func(nodeName string) (node.Node, bool, error) {
+		var eksNode *v1.Node
+		err := upstream.Retry(ctx, "kubernetes:GetNode", func() (err error) {
+			eksNode, err = clientset.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+			return err
+		})
+		if apierrors.IsNotFound(err) {
+			return node.Node{}, false, nil
+		}
+		if err != nil {
+			return node.Node{}, false, newError("eks", c.Name, "kubernetes:GetNode", err)
+		}
+
+		currentNode := normalizeEksNode(eksNode, c)
+		return currentNode, currentNode.Matches(name), nil
+	}
+
+	// Nodes are usually looked up by name, so the name is tried before an
+	// instance ID or IP is resolved to node names
+	if currentNode, ok, err := getNode(name); err != nil || ok {
+		return currentNode, err
+	}
+
+	nodeNames, err := p.nodeNames(ctx, c, name)
+	if err != nil {
+		return node.Node{}, err
+	}
+	for _, nodeName := range nodeNames {
+		if currentNode, ok, err := getNode(nodeName); err != nil || ok {
+			return currentNode, err
+		}
+	}
+	if !nodeUID.MatchString(name) {
+		return node.Node{}, notFound("eks", c.Name, "kubernetes:GetNode", "node %s not found", name)
+	}
+
+	eksNodes, err := p.nodes(ctx, clientset, c)
+	if err != nil {
+		return node.Node{}, err
+	}
+
+	for i := range eksNodes {
+		if currentNode := normalizeEksNode(&eksNodes[i], c); currentNode.Matches(name) {
+			return currentNode, nil
+		}
+	}
+
+	return node.Node{}, notFound("eks", c.Name, "kubernetes:ListNodes", "node %s not found", name)
+}
+
+// nodeUID matches the UUIDs Kubernetes gives nodes as UIDs
+var nodeUID = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// instanceID matches EC2 instance IDs, e.g. i-0123456789abcdef0, but not
+// node names that start with one
+var instanceID = regexp.MustCompile(`^i-[0-9a-f]{8,17}$`)
+
+// nodeNames returns the node names an instance ID or IP may stand for. EKS
+// names nodes after the private DNS name of their instance, which EC2 reports
+// for instance IDs and IPs; without EC2 it is derived from the IP.
+func (p *EKS) nodeNames(ctx context.Context, c cluster.Cluster, id string) ([]string, error) {
+	ip := net.ParseIP(id)
+	isInstance := instanceID.MatchString(id)
+	if ip == nil && !isInstance {
+		return nil, nil
+	}
+
+	if p.ec2 != nil {
+		input := &ec2.DescribeInstancesInput{}
+		if isInstance {
+			input.InstanceIds = []*string{aws.String(id)}
+		} else {
+			input.Filters = []*ec2.Filter{{Name: aws.String("private-ip-address"), Values: []*string{aws.String(id)}}}
+		}
+
+		// ec2:DescribeInstances. InvalidInstanceID.NotFound and .Malformed mean
+		// the identifier isn't the ID of an instance EC2 knows about.
+		resultDescribeInstances, err := p.ec2.DescribeInstancesWithContext(ctx, input)
+		if awsErr, ok := err.(awserr.Error); ok && strings.HasPrefix(awsErr.Code(), "InvalidInstanceID.") {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, newError("eks", c.Name, "ec2:DescribeInstances", err)
+		}
+
+		var nodeNames []string
+		for _, reservation := range resultDescribeInstances.Reservations {
+			for _, instance := range reservation.Instances {
+				nodeNames = append(nodeNames, aws.StringValue(instance.PrivateDnsName))
+			}
+		}
+		return nodeNames, nil
+	}
+
+	if ip == nil || ip.To4() == nil {
+		return nil, nil
+	}
+
+	// us-east-1 uses ec2.internal, other regions <region>.compute.internal
+	host := "ip-" + strings.Replace(ip.String(), ".", "-", -1)
+	domain := c.Region + ".compute.internal"
+	if c.Region == "us-east-1" {
+		domain = "ec2.internal"
+	}
+	return []string{host + "." + domain}, nil
+}
+
+// ListServices lists the Kubernetes services of an EKS cluster across the
+// namespaces allowed by the namespace filter. Fargate profiles that can't be
+// read are reported along with the services.
+func (p *EKS) ListServices(ctx context.Context, c cluster.Cluster) ([]service.Service, error) {
+	clientset, err := p.kubeClient(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	eksServices, err := p.services(ctx, clientset, c)
+	if err != nil {
+		return nil, err
+	}
+
+	// Endpoints share the namespace and name of their service, and services
+	// only select controllers in their own namespace, so both are listed
+	// only in the namespaces that have services
+	endpoints := map[string]*v1.Endpoints{}
+	controllersByNamespace := map[string][]eksController{}
+	for _, namespace := range eksNamespaces(eksServices) {
+		eksEndpoints, err := p.namespaceEndpoints(ctx, clientset, c, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for i := range eksEndpoints {
+			endpoints[namespace+"/"+eksEndpoints[i].Name] = &eksEndpoints[i]
+		}
+
+		eksDeployments, err := p.namespaceDeployments(ctx, clientset, c, namespace)
+		if err != nil {
+			return nil, err
+		}
+		eksStatefulSets, err := p.namespaceStatefulSets(ctx, clientset, c, namespace)
+		if err != nil {
+			return nil, err
+		}
+		eksDaemonSets, err := p.namespaceDaemonSets(ctx, clientset, c, namespace)
+		if err != nil {
+			return nil, err
+		}
+		controllersByNamespace[namespace] = eksControllers(eksDeployments, eksStatefulSets, eksDaemonSets, c)
+	}
+
+	// Pods, the nodes they run on and Fargate profiles tell where the pods
+	// of each service run. Pods are listed in the namespaces of services
+	// with a selector, and only the nodes of the selected pods are read.
+	podsByNamespace := map[string][]v1.Pod{}
+	var selectedPods []v1.Pod
+	for _, namespace := range eksNamespaces(eksSelectors(eksServices)) {
+		eksPods, err := p.namespacePods(ctx, clientset, c, namespace, nil)
+		if err != nil {
+			return nil, err
+		}
+		podsByNamespace[namespace] = eksPods
+		selectedPods = append(selectedPods, eksSelectedPods(eksServices, eksPods)...)
+	}
+	nodesByName, err := p.podNodes(ctx, clientset, c, selectedPods)
+	if err != nil {
+		return nil, err
+	}
+
+	// Services are still placed by their pods and nodes when the Fargate
+	// profiles can't be read, and the error is returned with them
+	profiles, profileErr := p.fargateProfiles(ctx, c)
+
+	services := make([]service.Service, len(eksServices))
+	for i, eksService := range eksServices {
+		services[i] = normalizeEksService(eksService, endpoints[eksService.Namespace+"/"+eksService.Name], controllersByNamespace[eksService.Namespace], c)
+		services[i].LaunchType, services[i].CapacityProvider = eksServiceLaunchType(eksService, podsByNamespace[eksService.Namespace], nodesByName, profiles)
+	}
+
+	return services, profileErr
+}
+
+// services lists the Kubernetes services of an EKS cluster across the
+// namespaces allowed by the namespace filter
+func (p *EKS) services(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster) ([]v1.Service, error) {
+	eksServices := []v1.Service{}
+	err := p.listNamespaced(ctx, c, "kubernetes:ListServices", func(namespace string, opts metav1.ListOptions) (string, error) {
+		page, err := clientset.CoreV1().Services(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksServices = append(eksServices, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eksServices, nil
+}
+
+// eksSelectors returns the services that select pods
+func eksSelectors(eksServices []v1.Service) []v1.Service {
+	var selectors []v1.Service
+	for _, eksService := range eksServices {
+		if len(eksService.Spec.Selector) > 0 {
+			selectors = append(selectors, eksService)
+		}
+	}
+	return selectors
+}
+
+// eksSelectedPods returns the pods selected by any of the services
+func eksSelectedPods(eksServices []v1.Service, eksPods []v1.Pod) []v1.Pod {
+	var selected []v1.Pod
+	for _, eksPod := range eksPods {
+		for _, eksService := range eksServices {
+			if len(eksService.Spec.Selector) > 0 && eksService.Namespace == eksPod.Namespace &&
+				labels.SelectorFromSet(eksService.Spec.Selector).Matches(labels.Set(eksPod.Labels)) {
+				selected = append(selected, eksPod)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// eksNamespaces returns the namespaces of services, in the order they first
+// appear
+func eksNamespaces(eksServices []v1.Service) []string {
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, eksService := range eksServices {
+		if !seen[eksService.Namespace] {
+			seen[eksService.Namespace] = true
+			namespaces = append(namespaces, eksService.Namespace)
+		}
+	}
+	return namespaces
+}
+
+// namespaceEndpoints lists the Endpoints of a namespace of an EKS cluster
+func (p *EKS) namespaceEndpoints(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster, namespace string) ([]v1.Endpoints, error) {
+	eksEndpoints := []v1.Endpoints{}
+	err := listPages(ctx, c, "kubernetes:ListEndpoints", metav1.ListOptions{}, func(opts metav1.ListOptions) (string, error) {
+		page, err := clientset.CoreV1().Endpoints(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksEndpoints = append(eksEndpoints, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eksEndpoints, nil
+}
+
+// deployments lists the Deployments of an EKS cluster across the namespaces
+// allowed by the namespace filter
+func (p *EKS) deployments(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster) ([]appsv1.Deployment, error) {
+	eksDeployments := []appsv1.Deployment{}
+	err := p.listNamespaced(ctx, c, "kubernetes:ListDeployments", func(namespace string, opts metav1.ListOptions) (string, error) {
+		page, err := clientset.AppsV1().Deployments(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksDeployments = append(eksDeployments, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eksDeployments, nil
+}
+
+// statefulSets lists the StatefulSets of an EKS cluster across the namespaces
+// allowed by the namespace filter
+func (p *EKS) statefulSets(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster) ([]appsv1.StatefulSet, error) {
+	eksStatefulSets := []appsv1.StatefulSet{}
+	err := p.listNamespaced(ctx, c, "kubernetes:ListStatefulSets", func(namespace string, opts metav1.ListOptions) (string, error) {
+		page, err := clientset.AppsV1().StatefulSets(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksStatefulSets = append(eksStatefulSets, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eksStatefulSets, nil
+}
+
+// daemonSets lists the DaemonSets of an EKS cluster across the namespaces
+// allowed by the namespace filter
+func (p *EKS) daemonSets(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster) ([]appsv1.DaemonSet, error) {
+	eksDaemonSets := []appsv1.DaemonSet{}
+	err := p.listNamespaced(ctx, c, "kubernetes:ListDaemonSets", func(namespace string, opts metav1.ListOptions) (string, error) {
+		page, err := clientset.AppsV1().DaemonSets(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksDaemonSets = append(eksDaemonSets, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eksDaemonSets, nil
+}
+
+// pods lists the pods of an EKS cluster across the namespaces allowed by the
+// namespace filter
+func (p *EKS) pods(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster) ([]v1.Pod, error) {
+	eksPods := []v1.Pod{}
+	err := p.listNamespaced(ctx, c, "kubernetes:ListPods", func(namespace string, opts metav1.ListOptions) (string, error) {
+		page, err := clientset.CoreV1().Pods(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksPods = append(eksPods, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eksPods, nil
+}
+
+// namespacePods lists the pods of a namespace of an EKS cluster, only those
+// with the given labels when there are any
+func (p *EKS) namespacePods(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster, namespace string, podLabels map[string]string) ([]v1.Pod, error) {
+	opts := metav1.ListOptions{}
+	if len(podLabels) > 0 {
+		opts.LabelSelector = labels.SelectorFromSet(podLabels).String()
+	}
+
+	eksPods := []v1.Pod{}
+	err := listPages(ctx, c, "kubernetes:ListPods", opts, func(opts metav1.ListOptions) (string, error) {
+		page, err := clientset.CoreV1().Pods(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksPods = append(eksPods, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eksPods, nil
+}
+
+// podNodes gets the nodes pods run on, indexed by name. Pods placed by the
+// Fargate scheduler are known to run on Fargate, so their nodes aren't read,
+// and nodes that no longer exist are left out.
+func (p *EKS) podNodes(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster, eksPods []v1.Pod) (map[string]*v1.Node, error) {
+	var nodeNames []string
+	seen := map[string]bool{}
+	for _, eksPod := range eksPods {
+		nodeName := eksPod.Spec.NodeName
+		if nodeName == "" || seen[nodeName] || eksPod.Spec.SchedulerName == eksFargateScheduler || eksPod.Labels[eksFargateProfileLabel] != "" {
+			continue
+		}
+		seen[nodeName] = true
+		nodeNames = append(nodeNames, nodeName)
+	}
+
+	eksNodes := make([]*v1.Node, len(nodeNames))
+	errs := forEach(ctx, len(nodeNames), func(i int) error {
+		return upstream.Retry(ctx, "kubernetes:GetNode", func() (err error) {
+			eksNodes[i], err = clientset.CoreV1().Nodes().Get(nodeNames[i], metav1.GetOptions{})
+			return err
+		})
+	})
+
+	index := make(map[string]*v1.Node, len(nodeNames))
+	for i, err := range errs {
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, newError("eks", c.Name, "kubernetes:GetNode", err)
+		}
+		index[nodeNames[i]] = eksNodes[i]
+	}
+	return index, nil
+}
+
+// nodes lists the nodes of an EKS cluster
+func (p *EKS) nodes(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster) ([]v1.Node, error) {
+	eksNodes := []v1.Node{}
+	err := listPages(ctx, c, "kubernetes:ListNodes", metav1.ListOptions{}, func(opts metav1.ListOptions) (string, error) {
+		page, err := clientset.CoreV1().Nodes().List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksNodes = append(eksNodes, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eksNodes, nil
+}
+
+// namespaceDeployments lists the Deployments of a namespace of an EKS cluster
+func (p *EKS) namespaceDeployments(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster, namespace string) ([]appsv1.Deployment, error) {
+	eksDeployments := []appsv1.Deployment{}
+	err := listPages(ctx, c, "kubernetes:ListDeployments", metav1.ListOptions{}, func(opts metav1.ListOptions) (string, error) {
+		page, err := clientset.AppsV1().Deployments(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksDeployments = append(eksDeployments, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eksDeployments, nil
+}
+
+// namespaceStatefulSets lists the StatefulSets of a namespace of an EKS
+// cluster
+func (p *EKS) namespaceStatefulSets(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster, namespace string) ([]appsv1.StatefulSet, error) {
+	eksStatefulSets := []appsv1.StatefulSet{}
+	err := listPages(ctx, c, "kubernetes:ListStatefulSets", metav1.ListOptions{}, func(opts metav1.ListOptions) (string, error) {
+		page, err := clientset.AppsV1().StatefulSets(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksStatefulSets = append(eksStatefulSets, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eksStatefulSets, nil
+}
+
+// namespaceDaemonSets lists the DaemonSets of a namespace of an EKS cluster
+func (p *EKS) namespaceDaemonSets(ctx context.Context, clientset kubernetes.Interface, c cluster.Cluster, namespace string) ([]appsv1.DaemonSet, error) {
+	eksDaemonSets := []appsv1.DaemonSet{}
+	err := listPages(ctx, c, "kubernetes:ListDaemonSets", metav1.ListOptions{}, func(opts metav1.ListOptions) (string, error) {
+		page, err := clientset.AppsV1().DaemonSets(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksDaemonSets = append(eksDaemonSets, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eksDaemonSets, nil
+}
+
+// byName indexes nodes by name, which is how pods refer to them
+func byName(eksNodes []v1.Node) map[string]*v1.Node {
+	index := make(map[string]*v1.Node, len(eksNodes))
+	for i := range eksNodes {
+		index[eksNodes[i].Name] = &eksNodes[i]
+	}
+	return index
+}
+
+// DescribeService describes a Kubernetes service along with the workload
+// controllers whose pods it selects and the rollouts of its Deployments
+func (p *EKS) DescribeService(ctx context.Context, c cluster.Cluster, namespace, name string) (service.Detail, error) {
+	if !p.namespaceFilter.Allows(namespace) {
+		return service.Detail{}, notFound("eks", c.Name, "kubernetes:GetService", "service %s/%s not found", namespace, name)
+	}
+
+	clientset, err := p.kubeClient(ctx, c)
+	if err != nil {
+		return service.Detail{}, err
+	}
+
+	var eksService *v1.Service
+	err = upstream.Retry(ctx, "kubernetes:GetService", func() (err error) {
+		eksService, err = clientset.CoreV1().Services(namespace).Get(name, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return service.Detail{}, newError("eks", c.Name, "kubernetes:GetService", err)
+	}
+
+	var eksEndpoints *v1.Endpoints
+	err = upstream.Retry(ctx, "kubernetes:GetEndpoints", func() (err error) {
+		eksEndpoints, err = clientset.CoreV1().Endpoints(namespace).Get(name, metav1.GetOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		eksEndpoints, err = nil, nil
+	}
+	if err != nil {
+		return service.Detail{}, newError("eks", c.Name, "kubernetes:GetEndpoints", err)
+	}
+
+	eksDeployments, err := p.namespaceDeployments(ctx, clientset, c, namespace)
+	if err != nil {
+		return service.Detail{}, err
+	}
+	eksStatefulSets, err := p.namespaceStatefulSets(ctx, clientset, c, namespace)
+	if err != nil {
+		return service.Detail{}, err
+	}
+	eksDaemonSets, err := p.namespaceDaemonSets(ctx, clientset, c, namespace)
+	if err != nil {
+		return service.Detail{}, err
+	}
+
+	// Only the pods the service selects and the nodes they run on are read
+	var eksPods []v1.Pod
+	if len(eksService.Spec.Selector) > 0 {
+		eksPods, err = p.namespacePods(ctx, clientset, c, namespace, eksService.Spec.Selector)
+		if err != nil {
+			return service.Detail{}, err
+		}
+	}
+	nodesByName, err := p.podNodes(ctx, clientset, c, eksPods)
+	if err != nil {
+		return service.Detail{}, err
+	}
+
+	// The service is still placed by its pods and nodes when the Fargate
+	// profiles can't be read; the error is logged
+	profiles, _ := p.fargateProfiles(ctx, c)
+
+	detail := service.Detail{
+		Service:     normalizeEksService(*eksService, eksEndpoints, eksControllers(eksDeployments, eksStatefulSets, eksDaemonSets, c), c),
+		Deployments: []service.Deployment{},
+		Conditions:  []service.Condition{},
+	}
+	detail.LaunchType, detail.CapacityProvider = eksServiceLaunchType(*eksService, eksPods, nodesByName, profiles)
+	if len(eksService.Spec.Selector) == 0 {
+		return detail, nil
+	}
+
+	// Deployments are listed by name, so the first match is stable
+	selector := labels.SelectorFromSet(eksService.Spec.Selector)
+	for i := range eksDeployments {
+		eksDeployment := &eksDeployments[i]
+		if !selector.Matches(labels.Set(eksDeployment.Spec.Template.Labels)) {
+			continue
+		}
+
+		replicaSetSelector, err := metav1.LabelSelectorAsSelector(eksDeployment.Spec.Selector)
+		if err != nil {
+			return service.Detail{}, newError("eks", c.Name, "kubernetes:ListReplicaSets", err)
+		}
+
+		var eksReplicaSets *appsv1.ReplicaSetList
+		err = upstream.Retry(ctx, "kubernetes:ListReplicaSets", func() (err error) {
+			eksReplicaSets, err = clientset.AppsV1().ReplicaSets(namespace).List(metav1.ListOptions{LabelSelector: replicaSetSelector.String()})
+			return err
+		})
+		if err != nil {
+			return service.Detail{}, newError("eks", c.Name, "kubernetes:ListReplicaSets", err)
+		}
+
+		return normalizeEksDeployment(detail, eksDeployment, eksReplicaSets.Items), nil
+	}
+
+	return detail, nil
+}
+
+// eksRevision is the annotation holding the rollout revision of Deployments
+// and their ReplicaSets
+const eksRevision = "deployment.kubernetes.io/revision"
+
+// normalizeEksDeployment fills a service detail from the Deployment the
+// service selects and the ReplicaSets it owns, newest first
+func normalizeEksDeployment(detail service.Detail, eksDeployment *appsv1.Deployment, eksReplicaSets []appsv1.ReplicaSet) service.Detail {
+	detail.Controller = "Deployment/" + eksDeployment.Name
+	detail.TaskDefinition = detail.Controller + ":" + eksDeployment.Annotations[eksRevision]
+	if eksDeployment.Spec.Replicas != nil {
+		detail.DesiredCount = int64(*eksDeployment.Spec.Replicas)
+	}
+	detail.RunningCount = int64(eksDeployment.Status.ReadyReplicas)
+	detail.PendingCount = pending(eksDeployment.Status.Replicas, eksDeployment.Status.ReadyReplicas)
+
+	detail.DeploymentConfiguration.Strategy = string(eksDeployment.Spec.Strategy.Type)
+	if rollingUpdate := eksDeployment.Spec.Strategy.RollingUpdate; rollingUpdate != nil {
+		if rollingUpdate.MaxSurge != nil {
+			detail.DeploymentConfiguration.MaxSurge = rollingUpdate.MaxSurge.String()
+		}
+		if rollingUpdate.MaxUnavailable != nil {
+			detail.DeploymentConfiguration.MaxUnavailable = rollingUpdate.MaxUnavailable.String()
+		}
+	}
+
+	// The Progressing condition tells whether the rollout completed or failed
+	rolloutState, rolloutStateReason := "IN_PROGRESS", ""
+	for _, eksCondition := range eksDeployment.Status.Conditions {
+		lastUpdateTime := eksCondition.LastUpdateTime.Time
+		detail.Conditions = append(detail.Conditions, service.Condition{
+			Type:           string(eksCondition.Type),
+			Status:         string(eksCondition.Status),
+			Reason:         eksCondition.Reason,
+			Message:        eksCondition.Message,
+			LastUpdateTime: &lastUpdateTime,
+		})
+
+		if eksCondition.Type != appsv1.DeploymentProgressing {
+			continue
+		}
+		rolloutStateReason = eksCondition.Message
+		switch eksCondition.Reason {
+		case "NewReplicaSetAvailable":
+			rolloutState = "COMPLETED"
+		case "ProgressDeadlineExceeded":
+			rolloutState = "FAILED"
+		}
+	}
+
+	revision := eksDeployment.Annotations[eksRevision]
+	for _, eksReplicaSet := range eksReplicaSets {
+		if !metav1.IsControlledBy(&eksReplicaSet, eksDeployment) {
+			continue
+		}
+
+		createdAt := eksReplicaSet.CreationTimestamp.Time
+		deployment := service.Deployment{
+			ID:           eksReplicaSet.Name,
+			Status:       "INACTIVE",
+			Revision:     eksReplicaSet.Annotations[eksRevision],
+			RunningCount: int64(eksReplicaSet.Status.ReadyReplicas),
+			PendingCount: pending(eksReplicaSet.Status.Replicas, eksReplicaSet.Status.ReadyReplicas),
+			CreatedAt:    &createdAt,
+		}
+		if eksReplicaSet.Spec.Replicas != nil {
+			deployment.DesiredCount = int64(*eksReplicaSet.Spec.Replicas)
+		}
+
+		switch {
+		case deployment.Revision == revision:
+			deployment.Status = "PRIMARY"
+			deployment.RolloutState = rolloutState
+			deployment.RolloutStateReason = rolloutStateReason
+		case eksReplicaSet.Status.Replicas > 0:
+			deployment.Status = "ACTIVE"
+		}
+		detail.Deployments = append(detail.Deployments, deployment)
+	}
+
+	sort.SliceStable(detail.Deployments, func(i, j int) bool {
+		a, _ := strconv.Atoi(detail.Deployments[i].Revision)
+		b, _ := strconv.Atoi(detail.Deployments[j].Revision)
+		return a > b
+	})
+
+	return detail
+}
+
+// pending is the number of replicas that exist but aren't ready yet
+func pending(replicas, ready int32) int64 {
+	if replicas < ready {
+		return 0
+	}
+	return int64(replicas - ready)
+}
+
+// ListWorkloads lists the Deployments, StatefulSets, DaemonSets, CronJobs and
+// Jobs of an EKS cluster across the namespaces allowed by the namespace
+// filter, each with the services that select its pods. Jobs started by a
+// CronJob are left to their CronJob.
+func (p *EKS) ListWorkloads(ctx context.Context, c cluster.Cluster) ([]workload.Workload, error) {
+	clientset, err := p.kubeClient(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	// One paginated List call per resource across all namespaces
+	eksServices, err := p.services(ctx, clientset, c)
+	if err != nil {
+		return nil, err
+	}
+	eksDeployments, err := p.deployments(ctx, clientset, c)
+	if err != nil {
+		return nil, err
+	}
+
+	eksStatefulSets, err := p.statefulSets(ctx, clientset, c)
+	if err != nil {
+		return nil, err
+	}
+	eksDaemonSets, err := p.daemonSets(ctx, clientset, c)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clusters that no longer serve batch/v1beta1 have no CronJobs to list
+	var eksCronJobs []batchv1beta1.CronJob
+	err = p.listNamespaced(ctx, c, "kubernetes:ListCronJobs", func(namespace string, opts metav1.ListOptions) (string, error) {
+		page, err := clientset.BatchV1beta1().CronJobs(namespace).List(opts)
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		eksCronJobs = append(eksCronJobs, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var eksJobs []batchv1.Job
+	err = p.listNamespaced(ctx, c, "kubernetes:ListJobs", func(namespace string, opts metav1.ListOptions) (string, error) {
+		page, err := clientset.BatchV1().Jobs(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		eksJobs = append(eksJobs, page.Items...)
+		return page.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	servicesByNamespace := map[string][]v1.Service{}
+	for _, eksService := range eksServices {
+		servicesByNamespace[eksService.Namespace] = append(servicesByNamespace[eksService.Namespace], eksService)
+	}
+
+	workloads := []workload.Workload{}
+	for _, eksDeployment := range eksDeployments {
+		workloads = append(workloads, normalizeEksDeploymentWorkload(eksDeployment, servicesByNamespace[eksDeployment.Namespace], c))
+	}
+	for _, eksStatefulSet := range eksStatefulSets {
+		workloads = append(workloads, normalizeEksStatefulSet(eksStatefulSet, servicesByNamespace[eksStatefulSet.Namespace], c))
+	}
+	for _, eksDaemonSet := range eksDaemonSets {
+		workloads = append(workloads, normalizeEksDaemonSet(eksDaemonSet, servicesByNamespace[eksDaemonSet.Namespace], c))
+	}
+	for _, eksCronJob := range eksCronJobs {
+		workloads = append(workloads, normalizeEksCronJob(eksCronJob, servicesByNamespace[eksCronJob.Namespace], c))
+	}
+	for _, eksJob := range eksJobs {
+		if owner := metav1.GetControllerOf(&eksJob); owner != nil && owner.Kind == "CronJob" {
+			continue
+		}
+		workloads = append(workloads, normalizeEksJob(eksJob, servicesByNamespace[eksJob.Namespace], c))
+	}
+
+	// List calls return objects by namespace, so keep every kind together
+	// within its namespace
+	sort.SliceStable(workloads, func(i, j int) bool {
+		return workloads[i].Namespace < workloads[j].Namespace
+	})
+
+	return workloads, nil
+}
+
+// ListTasks lists the Kubernetes pods of an EKS cluster across all
+// namespaces
+func (p *EKS) ListTasks(ctx context.Context, c cluster.Cluster) ([]task.Task, error) {
+	clientset, err := p.kubeClient(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	eksPods, err := p.pods(ctx, clientset, c)
+	if err != nil {
+		return nil, err
+	}
+	eksNodes, err := p.nodes(ctx, clientset, c)
+	if err != nil {
+		return nil, err
+	}
+	nodesByName := byName(eksNodes)
+
+	tasks := make([]task.Task, len(eksPods))
+	for i := range eksPods {
+		tasks[i] = normalizeEksPod(&eksPods[i], nodesByName, c)
+	}
+
+	return tasks, nil
+}
+
+// DescribeTask describes a single Kubernetes pod by its ID, namespace/name
+func (p *EKS) DescribeTask(ctx context.Context, c cluster.Cluster, id string) (task.Task, error) {
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 || !p.namespaceFilter.Allows(parts[0]) {
+		return task.Task{}, notFound("eks", c.Name, "kubernetes:GetPod", "pod %s not found", id)
+	}
+	namespace, name := parts[0], parts[1]
+
+	clientset, err := p.kubeClient(ctx, c)
+	if err != nil {
+		return task.Task{}, err
+	}
+
+	var eksPod *v1.Pod
+	err = upstream.Retry(ctx, "kubernetes:GetPod", func() (err error) {
+		eksPod, err = clientset.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return task.Task{}, notFound("eks", c.Name, "kubernetes:GetPod", "pod %s not found", id)
+	}
+	if err != nil {
+		return task.Task{}, newError("eks", c.Name, "kubernetes:GetPod", err)
+	}
+
+	// The node of the pod tells whether it runs on Fargate
+	nodesByName := map[string]*v1.Node{}
+	if eksPod.Spec.NodeName != "" {
+		var eksNode *v1.Node
+		err = upstream.Retry(ctx, "kubernetes:GetNode", func() (err error) {
+			eksNode, err = clientset.CoreV1().Nodes().Get(eksPod.Spec.NodeName, metav1.GetOptions{})
+			return err
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return task.Task{}, newError("eks", c.Name, "kubernetes:GetNode", err)
+		}
+		if err == nil {
+			nodesByName[eksNode.Name] = eksNode
+		}
+	}
+
+	return normalizeEksPod(eksPod, nodesByName, c), nil
+}
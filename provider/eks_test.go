@@ -0,0 +1,656 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/buzzsurfr/harbormaster/upstream"
+	"github.com/buzzsurfr/harbormaster/workload"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeEKS paginates ListClusters and describes any cluster or Fargate profile
+// it lists, except those given an error in describeErrs
+type fakeEKS struct {
+	eksiface.EKSAPI
+	pageSize     int
+	clusters     []string
+	profiles     []*eks.FargateProfile
+	describeErrs map[string]error
+}
+
+func (f *fakeEKS) ListClustersWithContext(ctx aws.Context, input *eks.ListClustersInput, opts ...request.Option) (*eks.ListClustersOutput, error) {
+	names, next := page(f.clusters, input.NextToken, f.pageSize)
+	return &eks.ListClustersOutput{Clusters: names, NextToken: next}, nil
+}
+
+func (f *fakeEKS) DescribeClusterWithContext(ctx aws.Context, input *eks.DescribeClusterInput, opts ...request.Option) (*eks.DescribeClusterOutput, error) {
+	if err := f.describeErrs[aws.StringValue(input.Name)]; err != nil {
+		return nil, err
+	}
+	return &eks.DescribeClusterOutput{Cluster: &eks.Cluster{
+		Name:   input.Name,
+		Arn:    aws.String("arn:aws:eks:us-east-1:123456789012:cluster/" + aws.StringValue(input.Name)),
+		Status: aws.String("ACTIVE"),
+	}}, nil
+}
+
+func (f *fakeEKS) ListFargateProfilesWithContext(ctx aws.Context, input *eks.ListFargateProfilesInput, opts ...request.Option) (*eks.ListFargateProfilesOutput, error) {
+	output := &eks.ListFargateProfilesOutput{}
+	for _, profile := range f.profiles {
+		output.FargateProfileNames = append(output.FargateProfileNames, profile.FargateProfileName)
+	}
+	return output, nil
+}
+
+func (f *fakeEKS) DescribeFargateProfileWithContext(ctx aws.Context, input *eks.DescribeFargateProfileInput, opts ...request.Option) (*eks.DescribeFargateProfileOutput, error) {
+	if err := f.describeErrs[aws.StringValue(input.FargateProfileName)]; err != nil {
+		return nil, err
+	}
+	for _, profile := range f.profiles {
+		if aws.StringValue(profile.FargateProfileName) == aws.StringValue(input.FargateProfileName) {
+			return &eks.DescribeFargateProfileOutput{FargateProfile: profile}, nil
+		}
+	}
+	return nil, awserr.New(eks.ErrCodeResourceNotFoundException, "fargate profile not found", nil)
+}
+
+func TestEKSListClustersPaginates(t *testing.T) {
+	fake := &fakeEKS{pageSize: 100, clusters: arns("cluster-%03d", 150)}
+
+	clusters, err := NewEKS(fake).ListClusters(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, clusters, 150)
+	assert.Equal(t, "cluster-149", clusters[149].Name)
+	assert.Equal(t, "eks", clusters[149].Scheduler)
+}
+
+func TestEKSListClustersKeepsDescribedClusters(t *testing.T) {
+	fake := &fakeEKS{
+		pageSize:     100,
+		clusters:     []string{"dev", "staging", "prod"},
+		describeErrs: map[string]error{"staging": awserr.New("AccessDeniedException", "not authorized", nil)},
+	}
+
+	clusters, err := NewEKS(fake).ListClusters(context.Background())
+	if assert.Len(t, clusters, 2) {
+		assert.Equal(t, "dev", clusters[0].Name)
+		assert.Equal(t, "prod", clusters[1].Name)
+	}
+	if assert.IsType(t, Errors{}, err) && assert.Len(t, err.(Errors), 1) {
+		assert.Equal(t, "staging", err.(Errors)[0].Cluster)
+		assert.Equal(t, "eks:DescribeCluster", err.(Errors)[0].Operation)
+	}
+
+	// The set lists the described clusters and reports the failed one
+	s := Set{NewEKS(fake)}
+	listed, errs := s.ListClusters(context.Background())
+	assert.Len(t, listed, 2)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "staging", errs[0].Cluster)
+		assert.Equal(t, upstream.AccessDenied, errs[0].Kind)
+	}
+}
+
+func TestNormalizeEksPod(t *testing.T) {
+	c := cluster.Cluster{Name: "prod", Scheduler: "eks"}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "web-7d9f-abcde",
+			Namespace:       "default",
+			UID:             "5e1f6a2c",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7d9f"}},
+		},
+		Spec: v1.PodSpec{
+			NodeName: "ip-10-0-1-23.ec2.internal",
+			Containers: []v1.Container{
+				{
+					Name:  "app",
+					Image: "web:1.4.2",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("250m"), v1.ResourceMemory: resource.MustParse("128Mi")},
+						Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("256Mi")},
+					},
+				},
+				{Name: "sidecar", Image: "envoy:1.8"},
+			},
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+			ContainerStatuses: []v1.ContainerStatus{
+				{
+					Name:         "app",
+					ImageID:      "docker-pullable://web@sha256:3f9a",
+					RestartCount: 2,
+					Ready:        true,
+					State:        v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, task.Task{
+		ID:            "default/web-7d9f-abcde",
+		Name:          "web-7d9f-abcde",
+		Scheduler:     "eks",
+		Cluster:       c,
+		Namespace:     "default",
+		Node:          "ip-10-0-1-23.ec2.internal",
+		Owner:         "ReplicaSet/web-7d9f",
+		DesiredStatus: "Running",
+		LastStatus:    "Running",
+		LaunchType:    "ec2",
+		Containers: []task.Container{
+			{
+				Name:         "app",
+				Image:        "web:1.4.2",
+				ImageDigest:  "sha256:3f9a",
+				Status:       "Running",
+				RestartCount: 2,
+				Health:       "HEALTHY",
+				Reservations: task.Resources{CPU: 250, Memory: 128},
+				Limits:       task.Resources{Memory: 256},
+			},
+			{Name: "sidecar", Image: "envoy:1.8", Status: "Waiting", Health: "UNKNOWN"},
+		},
+	}, normalizeEksPod(pod, nil, c))
+}
+
+func TestEKSDescribeService(t *testing.T) {
+	replicas := int32(3)
+	maxSurge := intstr.FromString("25%")
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "d1", Annotations: map[string]string{eksRevision: "2"}},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}}},
+			Strategy: appsv1.DeploymentStrategy{
+				Type:          appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: &maxSurge},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			Replicas:      4,
+			ReadyReplicas: 3,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentProgressing, Status: v1.ConditionTrue, Reason: "ReplicaSetUpdated"},
+			},
+		},
+	}
+	owner := []metav1.OwnerReference{*metav1.NewControllerRef(deployment, appsv1.SchemeGroupVersion.WithKind("Deployment"))}
+	replicaSet := func(name, revision string, replicas int32) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "default",
+				Labels:          map[string]string{"app": "web"},
+				Annotations:     map[string]string{eksRevision: revision},
+				OwnerReferences: owner,
+			},
+			Spec:   appsv1.ReplicaSetSpec{Replicas: &replicas},
+			Status: appsv1.ReplicaSetStatus{Replicas: replicas, ReadyReplicas: replicas},
+		}
+	}
+	clientset := k8sfake.NewSimpleClientset(
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       v1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+		},
+		deployment,
+		replicaSet("web-1", "1", 1),
+		replicaSet("web-2", "2", 3),
+	)
+	p := NewEKS(&fakeEKS{clusters: []string{"prod"}}).WithClientset(func(*eks.Cluster) (kubernetes.Interface, error) {
+		return clientset, nil
+	})
+
+	detail, err := p.DescribeService(context.Background(), cluster.Cluster{Name: "prod", Scheduler: "eks"}, "default", "web")
+	assert.Nil(t, err)
+	assert.Equal(t, "DEGRADED", detail.Status)
+	assert.Equal(t, "Deployment/web", detail.Controller)
+	assert.Equal(t, int64(3), detail.DesiredCount)
+	assert.Equal(t, int64(3), detail.RunningCount)
+	assert.Equal(t, int64(1), detail.PendingCount)
+	assert.Equal(t, service.DeploymentConfiguration{Strategy: "RollingUpdate", MaxSurge: "25%"}, detail.DeploymentConfiguration)
+	if assert.Len(t, detail.Deployments, 2) {
+		assert.Equal(t, "web-2", detail.Deployments[0].ID)
+		assert.Equal(t, "PRIMARY", detail.Deployments[0].Status)
+		assert.Equal(t, "IN_PROGRESS", detail.Deployments[0].RolloutState)
+		assert.Equal(t, "web-1", detail.Deployments[1].ID)
+		assert.Equal(t, "ACTIVE", detail.Deployments[1].Status)
+	}
+
+	// Pods are listed by the selector of the service and nodes aren't listed
+	for _, action := range clientset.Actions() {
+		if list, ok := action.(k8stesting.ListAction); ok {
+			assert.NotEqual(t, "nodes", list.GetResource().Resource)
+			if list.GetResource().Resource == "pods" {
+				assert.Equal(t, "app=web", list.GetListRestrictions().Labels.String())
+			}
+		}
+	}
+
+	_, err = p.DescribeService(context.Background(), cluster.Cluster{Name: "prod", Scheduler: "eks"}, "default", "missing")
+	if assert.IsType(t, &Error{}, err) {
+		assert.Equal(t, upstream.NotFound, err.(*Error).Kind)
+	}
+}
+
+func TestEKSListServicesByNamespace(t *testing.T) {
+	webLabels := map[string]string{"app": "web"}
+	clientset := k8sfake.NewSimpleClientset(
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       v1.ServiceSpec{Selector: webLabels},
+		},
+		&v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Subsets:    []v1.EndpointSubset{{Addresses: []v1.EndpointAddress{{IP: "10.0.1.5"}}}},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "jobs"},
+			Spec:       appsv1.DeploymentSpec{Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: webLabels}}},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: webLabels},
+			Spec:       v1.PodSpec{NodeName: "ip-10-0-1-23"},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "batch-1", Namespace: "jobs", Labels: webLabels},
+			Spec:       v1.PodSpec{NodeName: "ip-10-0-1-24"},
+		},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ip-10-0-1-23", Labels: map[string]string{eksCapacityTypeLabel: "SPOT"}}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ip-10-0-1-24"}},
+	)
+	p := NewEKS(&fakeEKS{}).WithClientset(func(*eks.Cluster) (kubernetes.Interface, error) {
+		return clientset, nil
+	})
+
+	services, err := p.ListServices(context.Background(), cluster.Cluster{Name: "prod", Scheduler: "eks"})
+	assert.Nil(t, err)
+	if assert.Len(t, services, 1) {
+		assert.Equal(t, "ACTIVE", services[0].Status)
+		assert.Equal(t, "SPOT", services[0].CapacityProvider)
+	}
+
+	// Only services are listed across namespaces, and only the nodes of the
+	// selected pods are read
+	for _, action := range clientset.Actions() {
+		switch {
+		case action.GetVerb() == "list" && action.GetResource().Resource != "services":
+			assert.Equal(t, "default", action.GetNamespace(), action.GetResource().Resource)
+		case action.GetVerb() == "get":
+			assert.Equal(t, "ip-10-0-1-23", action.(k8stesting.GetAction).GetName())
+		}
+	}
+}
+
+func TestNormalizeEksService(t *testing.T) {
+	replicas := int32(2)
+	eksService := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1.ServiceSpec{
+			Type:      v1.ServiceTypeLoadBalancer,
+			Selector:  map[string]string{"app": "web"},
+			ClusterIP: "172.20.14.3",
+			Ports: []v1.ServicePort{
+				{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, TargetPort: intstr.FromString("http"), NodePort: 31080},
+			},
+		},
+		Status: v1.ServiceStatus{LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{
+			{Hostname: "a1b2c3-123456789.us-east-1.elb.amazonaws.com"},
+		}}},
+	}
+	eksDeployments := []appsv1.Deployment{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{eksRevision: "4"}},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}}},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 1},
+	}}
+
+	c := cluster.Cluster{Name: "prod", Scheduler: "eks"}
+	s := normalizeEksService(eksService, nil, eksControllers(eksDeployments, nil, nil, c), c)
+	assert.Equal(t, "LoadBalancer", s.Type)
+	assert.Equal(t, map[string]string{"app": "web"}, s.Selector)
+	assert.Equal(t, []string{"172.20.14.3"}, s.ClusterIPs)
+	assert.Equal(t, []service.Port{{Name: "http", Protocol: "TCP", Port: 80, TargetPort: "http", NodePort: 31080}}, s.Ports)
+	assert.Equal(t, []service.LoadBalancer{{Hostname: "a1b2c3-123456789.us-east-1.elb.amazonaws.com"}}, s.LoadBalancers)
+	assert.Equal(t, "Deployment/web:4", s.TaskDefinition)
+	assert.Equal(t, int64(2), s.DesiredCount)
+	assert.Equal(t, int64(1), s.RunningCount)
+	assert.Equal(t, int64(1), s.PendingCount)
+
+	// Headless services have no cluster IP
+	eksService.Spec.ClusterIP = v1.ClusterIPNone
+	assert.Empty(t, normalizeEksService(eksService, nil, eksControllers(eksDeployments, nil, nil, c), c).ClusterIPs)
+
+	// StatefulSets and DaemonSets back services as well
+	eksStatefulSets := []appsv1.StatefulSet{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-cache", Namespace: "default", Generation: 3},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}}},
+		},
+		Status: appsv1.StatefulSetStatus{Replicas: 2, ReadyReplicas: 2},
+	}}
+	eksDaemonSets := []appsv1.DaemonSet{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-agent", Namespace: "default", Generation: 1},
+		Spec:       appsv1.DaemonSetSpec{Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}}}},
+		Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, CurrentNumberScheduled: 3, NumberReady: 3},
+	}}
+	s = normalizeEksService(eksService, nil, eksControllers(nil, eksStatefulSets, eksDaemonSets, c), c)
+	assert.Equal(t, "StatefulSet/web-cache:3", s.TaskDefinition)
+	assert.Equal(t, int64(5), s.DesiredCount)
+	assert.Equal(t, int64(5), s.RunningCount)
+}
+
+func TestEKSServiceStatus(t *testing.T) {
+	selected := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       v1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+	}
+	deleted := *selected.DeepCopy()
+	deleted.DeletionTimestamp = &metav1.Time{}
+	external := v1.Service{Spec: v1.ServiceSpec{Type: v1.ServiceTypeExternalName}}
+
+	endpoints := func(ready, notReady int) *v1.Endpoints {
+		return &v1.Endpoints{Subsets: []v1.EndpointSubset{{
+			Addresses:         make([]v1.EndpointAddress, ready),
+			NotReadyAddresses: make([]v1.EndpointAddress, notReady),
+		}}}
+	}
+	deployment := func(desired, ready int32) []eksController {
+		return eksControllers([]appsv1.Deployment{{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &desired,
+				Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}}},
+			},
+			Status: appsv1.DeploymentStatus{ReadyReplicas: ready},
+		}}, nil, nil, cluster.Cluster{})
+	}
+	statefulSet := func(desired, ready int32) []eksController {
+		return eksControllers(nil, []appsv1.StatefulSet{{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: &desired,
+				Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}}},
+			},
+			Status: appsv1.StatefulSetStatus{ReadyReplicas: ready},
+		}}, nil, cluster.Cluster{})
+	}
+
+	assert.Equal(t, "ACTIVE", eksServiceStatus(selected, endpoints(3, 0), deployment(3, 3)))
+	assert.Equal(t, "ACTIVE", eksServiceStatus(external, nil, nil))
+	assert.Equal(t, "DEGRADED", eksServiceStatus(selected, endpoints(2, 1), deployment(3, 2)))
+	assert.Equal(t, "DEGRADED", eksServiceStatus(selected, endpoints(3, 0), deployment(4, 3)))
+	assert.Equal(t, "DEGRADED", eksServiceStatus(selected, endpoints(0, 0), deployment(3, 0)))
+	assert.Equal(t, "DRAINING", eksServiceStatus(selected, endpoints(1, 0), deployment(0, 1)))
+	assert.Equal(t, "DRAINING", eksServiceStatus(deleted, endpoints(3, 0), deployment(3, 3)))
+	assert.Equal(t, "INACTIVE", eksServiceStatus(selected, endpoints(0, 0), deployment(0, 0)))
+	assert.Equal(t, "INACTIVE", eksServiceStatus(selected, nil, nil))
+	assert.Equal(t, "DEGRADED", eksServiceStatus(selected, endpoints(2, 0), statefulSet(3, 2)))
+	assert.Equal(t, "DRAINING", eksServiceStatus(selected, endpoints(1, 0), statefulSet(0, 1)))
+}
+
+func TestEKSDescribeTask(t *testing.T) {
+	clientset := k8sfake.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "fargate-ip-10-0-1-23", Labels: map[string]string{"eks.amazonaws.com/compute-type": "fargate"}}},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "web"},
+			Spec:       v1.PodSpec{NodeName: "fargate-ip-10-0-1-23"},
+		},
+	)
+	p := NewEKS(&fakeEKS{}).WithClientset(func(*eks.Cluster) (kubernetes.Interface, error) {
+		return clientset, nil
+	})
+	c := cluster.Cluster{Name: "prod", Scheduler: "eks"}
+
+	// The pod and its node are got directly rather than listed
+	k, err := p.DescribeTask(context.Background(), c, "web/web-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "web/web-1", k.ID)
+	assert.Equal(t, "fargate", k.LaunchType)
+	for _, action := range clientset.Actions() {
+		assert.Equal(t, "get", action.GetVerb())
+	}
+
+	for _, id := range []string{"web/web-2", "web-1"} {
+		_, err = p.DescribeTask(context.Background(), c, id)
+		if assert.IsType(t, &Error{}, err) {
+			assert.Equal(t, upstream.NotFound, err.(*Error).Kind)
+		}
+	}
+}
+
+func TestEKSCountCluster(t *testing.T) {
+	pod := func(name string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}, Status: v1.PodStatus{Phase: phase}}
+	}
+	clientset := k8sfake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ip-10-0-1-23"}},
+		pod("web-1", v1.PodRunning),
+		pod("web-2", v1.PodRunning),
+		pod("web-3", v1.PodPending),
+		pod("job-1", v1.PodSucceeded),
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+	)
+	p := NewEKS(&fakeEKS{}).WithClientset(func(*eks.Cluster) (kubernetes.Interface, error) {
+		return clientset, nil
+	})
+
+	// Describing a cluster doesn't read its Kubernetes API
+	c, err := p.DescribeCluster(context.Background(), "prod")
+	assert.Nil(t, err)
+	assert.Equal(t, "us-east-1", c.Region)
+	assert.Equal(t, "123456789012", c.AccountID)
+	assert.Nil(t, c.RegisteredNodes)
+	assert.Empty(t, clientset.Actions())
+
+	c, err = p.CountCluster(context.Background(), c)
+	assert.Nil(t, err)
+	assert.Equal(t, aws.Int64(1), c.RegisteredNodes)
+	assert.Equal(t, aws.Int64(2), c.RunningTasks)
+	assert.Equal(t, aws.Int64(1), c.PendingTasks)
+	assert.Equal(t, aws.Int64(1), c.ActiveServices)
+
+	// A cluster that can't be counted is listed without counts and reported
+	unreachable := NewEKS(&fakeEKS{}).WithClientset(func(*eks.Cluster) (kubernetes.Interface, error) {
+		return nil, errors.New("no route to host")
+	})
+	counted, errs := Set{unreachable}.CountClusters(context.Background(), []cluster.Cluster{{Name: "prod", Scheduler: "eks", Status: "ACTIVE"}})
+	if assert.Len(t, counted, 1) {
+		assert.Equal(t, "prod", counted[0].Name)
+		assert.Nil(t, counted[0].RunningTasks)
+	}
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "prod", errs[0].Cluster)
+		assert.Equal(t, "kubernetes:Connect", errs[0].Operation)
+	}
+}
+
+func TestNormalizeEksNode(t *testing.T) {
+	n := normalizeEksNode(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "ip-10-0-1-23.ec2.internal",
+			UID:  "8b0c2f7e",
+			Labels: map[string]string{
+				"beta.kubernetes.io/instance-type":       "m5.large",
+				"failure-domain.beta.kubernetes.io/zone": "us-east-1a",
+			},
+		},
+		Spec: v1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0123456789abcdef0"},
+		Status: v1.NodeStatus{
+			Conditions:  []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+			Addresses:   []v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "10.0.1.23"}},
+			NodeInfo:    v1.NodeSystemInfo{KubeletVersion: "v1.11.5", OSImage: "Amazon Linux 2"},
+			Capacity:    v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("8Gi")},
+			Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1930m"), v1.ResourceMemory: resource.MustParse("7Gi")},
+		},
+	}, cluster.Cluster{Name: "prod", Scheduler: "eks"})
+
+	assert.Equal(t, "ip-10-0-1-23.ec2.internal", n.Name)
+	assert.Equal(t, "8b0c2f7e", n.UID)
+	assert.Equal(t, "i-0123456789abcdef0", n.InstanceID)
+	assert.Equal(t, "Ready", n.Status)
+	assert.Equal(t, "m5.large", n.InstanceType)
+	assert.Equal(t, "us-east-1a", n.AvailabilityZone)
+	assert.Equal(t, "10.0.1.23", n.PrivateIP)
+	assert.Equal(t, "v1.11.5", n.AgentVersion)
+	assert.Equal(t, "Amazon Linux 2", n.OS)
+	assert.Equal(t, node.Resources{CPU: 2000, Memory: 8192}, n.Registered)
+	assert.Equal(t, node.Resources{CPU: 1930, Memory: 7168}, n.Remaining)
+}
+
+func TestEKSDescribeNode(t *testing.T) {
+	clientset := k8sfake.NewSimpleClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "ip-10-0-1-23.eu-west-1.compute.internal", UID: "8b0c2f7e-c5f6-11e8-a8d5-02d1b3c4e5f6"},
+		Spec:       v1.NodeSpec{ProviderID: "aws:///eu-west-1a/i-0123456789abcdef0"},
+		Status:     v1.NodeStatus{Addresses: []v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "10.0.1.23"}}},
+	})
+	p := NewEKS(&fakeEKS{}).WithClientset(func(*eks.Cluster) (kubernetes.Interface, error) {
+		return clientset, nil
+	})
+	c := cluster.Cluster{Name: "prod", Scheduler: "eks", Region: "eu-west-1"}
+
+	for _, id := range []string{"ip-10-0-1-23.eu-west-1.compute.internal", "10.0.1.23"} {
+		clientset.ClearActions()
+		n, err := p.DescribeNode(context.Background(), c, id)
+		assert.Nil(t, err)
+		assert.Equal(t, "ip-10-0-1-23.eu-west-1.compute.internal", n.Name)
+		for _, action := range clientset.Actions() {
+			assert.Equal(t, "get", action.GetVerb(), id)
+		}
+	}
+
+	// Only UIDs are found by listing nodes
+	n, err := p.DescribeNode(context.Background(), c, "8b0c2f7e-c5f6-11e8-a8d5-02d1b3c4e5f6")
+	assert.Nil(t, err)
+	assert.Equal(t, "ip-10-0-1-23.eu-west-1.compute.internal", n.Name)
+
+	for _, id := range []string{"10.0.9.9", "i-0123456789abcdef0", "8b0c2f7e"} {
+		clientset.ClearActions()
+		_, err := p.DescribeNode(context.Background(), c, id)
+		if assert.IsType(t, &Error{}, err) {
+			assert.Equal(t, upstream.NotFound, err.(*Error).Kind)
+		}
+		for _, action := range clientset.Actions() {
+			assert.Equal(t, "get", action.GetVerb(), id)
+		}
+	}
+
+	// Node names that start like an instance ID are got by name without asking
+	// EC2, and instance IDs EC2 doesn't know about aren't found
+	clientset.CoreV1().Nodes().Create(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "i-0abc123.eu-west-1.compute.internal"}})
+	ec2 := &fakeEC2{}
+	p.WithEC2(ec2)
+	n, err = p.DescribeNode(context.Background(), c, "i-0abc123.eu-west-1.compute.internal")
+	assert.Nil(t, err)
+	assert.Equal(t, "i-0abc123.eu-west-1.compute.internal", n.Name)
+	assert.Equal(t, 0, ec2.calls)
+
+	n, err = p.DescribeNode(context.Background(), c, "i-0123456789abcdef0")
+	assert.Nil(t, err)
+	assert.Equal(t, "ip-10-0-1-23.eu-west-1.compute.internal", n.Name)
+
+	_, err = p.DescribeNode(context.Background(), c, "i-0fedcba9876543210")
+	if assert.IsType(t, &Error{}, err) {
+		assert.Equal(t, upstream.NotFound, err.(*Error).Kind)
+	}
+}
+
+func TestEKSListWorkloads(t *testing.T) {
+	replicas := int32(3)
+	webLabels := map[string]string{"app": "web"}
+	cronJob := &batchv1beta1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "default", UID: "c1"}}
+	clientset := k8sfake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       v1.ServiceSpec{Selector: webLabels},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{eksRevision: "5"}},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: webLabels}},
+			},
+			Status: appsv1.DeploymentStatus{Replicas: 3, ReadyReplicas: 2, UpdatedReplicas: 3},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Generation: 2},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+			Status:     appsv1.StatefulSetStatus{Replicas: 3, ReadyReplicas: 3, UpdatedReplicas: 3},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "fluentd", Namespace: "default", Generation: 1},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, CurrentNumberScheduled: 2, NumberReady: 2, UpdatedNumberScheduled: 2},
+		},
+		cronJob,
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "report-1538395200",
+				Namespace:       "default",
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(cronJob, batchv1beta1.SchemeGroupVersion.WithKind("CronJob"))},
+			},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "default"},
+			Status: batchv1.JobStatus{
+				Succeeded:  1,
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}},
+			},
+		},
+	)
+	p := NewEKS(&fakeEKS{}).WithClientset(func(*eks.Cluster) (kubernetes.Interface, error) {
+		return clientset, nil
+	})
+
+	workloads, err := p.ListWorkloads(context.Background(), cluster.Cluster{Name: "prod", Scheduler: "eks"})
+	assert.Nil(t, err)
+
+	summary := make([]workload.Workload, len(workloads))
+	for i, w := range workloads {
+		summary[i] = workload.Workload{
+			Kind:           w.Kind,
+			Name:           w.Name,
+			Status:         w.Status,
+			DesiredCount:   w.DesiredCount,
+			RunningCount:   w.RunningCount,
+			PendingCount:   w.PendingCount,
+			TaskDefinition: w.TaskDefinition,
+			Services:       w.Services,
+		}
+	}
+	assert.Equal(t, []workload.Workload{
+		{Kind: "Deployment", Name: "web", Status: "DEGRADED", DesiredCount: 3, RunningCount: 2, PendingCount: 1, TaskDefinition: "Deployment/web:5", Services: []string{"web"}},
+		{Kind: "StatefulSet", Name: "db", Status: "ACTIVE", DesiredCount: 3, RunningCount: 3, TaskDefinition: "StatefulSet/db:2", Services: []string{}},
+		{Kind: "DaemonSet", Name: "fluentd", Status: "ACTIVE", DesiredCount: 2, RunningCount: 2, TaskDefinition: "DaemonSet/fluentd:1", Services: []string{}},
+		{Kind: "CronJob", Name: "report", Status: "ACTIVE", TaskDefinition: "CronJob/report:0", Services: []string{}},
+		{Kind: "Job", Name: "migrate", Status: "COMPLETED", DesiredCount: 1, TaskDefinition: "Job/migrate:0", Services: []string{}},
+	}, summary)
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/buzzsurfr/harbormaster/upstream"
+)
+
+// Error describes a discovery call that failed. Errors are reported next to
+// partial results, so a cluster that couldn't be read can be told apart from
+// one that has no nodes or services.
+type Error struct {
+	Scheduler string        `json:"scheduler"`
+	Cluster   string        `json:"cluster,omitempty"`
+	Operation string        `json:"operation"`
+	Kind      upstream.Kind `json:"kind,omitempty"`
+	Code      string        `json:"code,omitempty"`
+	Message   string        `json:"message"`
+	Err       error         `json:"-"`
+}
+
+func (e *Error) Error() string {
+	where := e.where()
+	if e.Code != "" {
+		return fmt.Sprintf("%s: %s: %s: %s", where, e.Operation, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", where, e.Operation, e.Message)
+}
+
+// where names the scheduler and cluster the error came from
+func (e *Error) where() string {
+	if e.Cluster != "" {
+		return e.Scheduler + "/" + e.Cluster
+	}
+	return e.Scheduler
+}
+
+// Errors is returned along with partial results when only some of the calls
+// behind them failed, e.g. one cluster out of many
+type Errors []*Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// orNil returns nil when no call failed, so that a nil error isn't returned
+// as a non-nil interface
+func (e Errors) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// CodeNotFound is the code of errors for clusters and nodes that don't exist
+const CodeNotFound = "NotFound"
+
+// ErrNotFound is wrapped by errors for clusters and nodes that don't exist
+var ErrNotFound = errors.New("not found")
+
+// notFound records a lookup that succeeded but matched nothing
+func notFound(scheduler, clusterName, operation, format string, args ...interface{}) *Error {
+	return &Error{
+		Scheduler: scheduler,
+		Cluster:   clusterName,
+		Operation: operation,
+		Kind:      upstream.NotFound,
+		Code:      CodeNotFound,
+		Message:   fmt.Sprintf(format, args...),
+		Err:       ErrNotFound,
+	}
+}
+
+// newError logs a failed call and records where it happened. Errors that
+// were already recorded are returned unchanged.
+func newError(scheduler, clusterName, operation string, err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+
+	code, message := upstream.Code(err)
+	e := &Error{
+		Scheduler: scheduler,
+		Cluster:   clusterName,
+		Operation: operation,
+		Kind:      upstream.Classify(err),
+		Code:      code,
+		Message:   message,
+		Err:       err,
+	}
+
+	upstream.Log(e.where(), operation, e.Kind, err)
+	return e
+}
+
+// errorsOf records the non-nil errors returned for each target
+func errorsOf(targets []target, operation string, errs []error) []*Error {
+	recorded := []*Error{}
+	for i, err := range errs {
+		if err != nil {
+			recorded = append(recorded, record(targets[i].provider.Scheduler(), targets[i].cluster.Name, operation, err)...)
+		}
+	}
+	return recorded
+}
+
+// record returns the errors behind a failed call: every entry of Errors, or
+// the error itself
+func record(scheduler, clusterName, operation string, err error) []*Error {
+	if errs, ok := err.(Errors); ok {
+		return errs
+	}
+	return []*Error{newError(scheduler, clusterName, operation, err)}
+}
@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Labels and scheduler name set by EKS on Fargate pods and on the nodes they
+// run on
+const (
+	eksComputeTypeLabel    = "eks.amazonaws.com/compute-type"
+	eksCapacityTypeLabel   = "eks.amazonaws.com/capacityType"
+	eksFargateProfileLabel = "eks.amazonaws.com/fargate-profile"
+	eksFargateScheduler    = "fargate-scheduler"
+)
+
+// ecsLaunchType returns the launch type and capacity providers of an ECS
+// service or task. Services using a capacity provider strategy have no
+// launch type, so it is inferred from the providers: FARGATE and
+// FARGATE_SPOT run on Fargate and any other provider on EC2.
+func ecsLaunchType(launchType *string, strategy []*ecs.CapacityProviderStrategyItem) (string, string) {
+	capacityProviders := make([]string, len(strategy))
+	for i, item := range strategy {
+		capacityProviders[i] = aws.StringValue(item.CapacityProvider)
+	}
+	capacityProvider := strings.Join(capacityProviders, ",")
+
+	if launchType != nil {
+		return strings.ToLower(aws.StringValue(launchType)), capacityProvider
+	}
+	if len(strategy) == 0 {
+		return "", capacityProvider
+	}
+	for _, name := range capacityProviders {
+		if !strings.HasPrefix(name, "FARGATE") {
+			return "ec2", capacityProvider
+		}
+	}
+	return "fargate", capacityProvider
+}
+
+// eksPodLaunchType returns the launch type and capacity provider of a pod
+// from the Fargate scheduler and profile it was given and from the labels of
+// the node it runs on. Pods on EC2 report the capacity type of their managed
+// node group, ON_DEMAND or SPOT.
+func eksPodLaunchType(eksPod *v1.Pod, eksNodes map[string]*v1.Node) (string, string) {
+	eksNode := eksNodes[eksPod.Spec.NodeName]
+	if eksPod.Spec.SchedulerName == eksFargateScheduler || eksPod.Labels[eksFargateProfileLabel] != "" ||
+		(eksNode != nil && eksNode.Labels[eksComputeTypeLabel] == "fargate") {
+		return "fargate", "FARGATE"
+	}
+	if eksNode != nil {
+		return "ec2", eksNode.Labels[eksCapacityTypeLabel]
+	}
+	return "ec2", ""
+}
+
+// eksServiceLaunchType returns where the pods selected by a service run:
+// "fargate" or "ec2" when they all run on one, "mixed" otherwise. Services
+// without running pods are placed by the Fargate profile their pods would
+// match, if any.
+func eksServiceLaunchType(eksService v1.Service, eksPods []v1.Pod, eksNodes map[string]*v1.Node, profiles []*eks.FargateProfile) (string, string) {
+	if len(eksService.Spec.Selector) == 0 {
+		return "", ""
+	}
+
+	var launchType, capacityProvider string
+	selector := labels.SelectorFromSet(eksService.Spec.Selector)
+	for i := range eksPods {
+		if eksPods[i].Namespace != eksService.Namespace || !selector.Matches(labels.Set(eksPods[i].Labels)) {
+			continue
+		}
+
+		podLaunchType, podCapacityProvider := eksPodLaunchType(&eksPods[i], eksNodes)
+		switch {
+		case launchType == "":
+			launchType, capacityProvider = podLaunchType, podCapacityProvider
+		case launchType != podLaunchType:
+			launchType, capacityProvider = "mixed", ""
+		case capacityProvider != podCapacityProvider:
+			capacityProvider = ""
+		}
+	}
+	if launchType != "" {
+		return launchType, capacityProvider
+	}
+
+	for _, profile := range profiles {
+		if fargateProfileMatches(profile, eksService.Namespace, eksService.Spec.Selector) {
+			return "fargate", "FARGATE"
+		}
+	}
+	return "ec2", ""
+}
+
+// fargateProfileMatches reports whether pods with the given namespace and
+// labels are scheduled on Fargate by a profile
+func fargateProfileMatches(profile *eks.FargateProfile, namespace string, podLabels map[string]string) bool {
+	for _, selector := range profile.Selectors {
+		if aws.StringValue(selector.Namespace) != namespace {
+			continue
+		}
+		matches := true
+		for k, v := range selector.Labels {
+			if podLabels[k] != aws.StringValue(v) {
+				matches = false
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// fargateProfiles lists and describes the Fargate profiles of an EKS cluster.
+// Profiles that can't be described are left out and reported in Errors.
+func (p *EKS) fargateProfiles(ctx context.Context, c cluster.Cluster) ([]*eks.FargateProfile, error) {
+	var profileNames []*string
+	input := &eks.ListFargateProfilesInput{
+		ClusterName: aws.String(c.Name),
+	}
+	for {
+		// eks:ListFargateProfiles
+		resultListFargateProfiles, err := p.svc.ListFargateProfilesWithContext(ctx, input)
+		if err != nil {
+			return nil, newError("eks", c.Name, "eks:ListFargateProfiles", err)
+		}
+
+		profileNames = append(profileNames, resultListFargateProfiles.FargateProfileNames...)
+		if aws.StringValue(resultListFargateProfiles.NextToken) == "" {
+			break
+		}
+		input.NextToken = resultListFargateProfiles.NextToken
+	}
+
+	described := make([]*eks.FargateProfile, len(profileNames))
+	describeErrs := forEach(ctx, len(profileNames), func(i int) error {
+		// eks:DescribeFargateProfile
+		resultDescribeFargateProfile, err := p.svc.DescribeFargateProfileWithContext(ctx, &eks.DescribeFargateProfileInput{
+			ClusterName:        aws.String(c.Name),
+			FargateProfileName: profileNames[i],
+		})
+		if err != nil {
+			return err
+		}
+		described[i] = resultDescribeFargateProfile.FargateProfile
+		return nil
+	})
+
+	var errs Errors
+	profiles := []*eks.FargateProfile{}
+	for i, err := range describeErrs {
+		if err != nil {
+			errs = append(errs, newError("eks", c.Name, "eks:DescribeFargateProfile", err))
+			continue
+		}
+		profiles = append(profiles, described[i])
+	}
+
+	return profiles, errs.orNil()
+}
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEcsLaunchType(t *testing.T) {
+	strategy := func(names ...string) []*ecs.CapacityProviderStrategyItem {
+		items := make([]*ecs.CapacityProviderStrategyItem, len(names))
+		for i, name := range names {
+			items[i] = &ecs.CapacityProviderStrategyItem{CapacityProvider: aws.String(name)}
+		}
+		return items
+	}
+
+	launchType, capacityProvider := ecsLaunchType(aws.String("FARGATE"), nil)
+	assert.Equal(t, "fargate", launchType)
+	assert.Equal(t, "", capacityProvider)
+
+	launchType, capacityProvider = ecsLaunchType(nil, strategy("FARGATE", "FARGATE_SPOT"))
+	assert.Equal(t, "fargate", launchType)
+	assert.Equal(t, "FARGATE,FARGATE_SPOT", capacityProvider)
+
+	launchType, capacityProvider = ecsLaunchType(nil, strategy("my-asg"))
+	assert.Equal(t, "ec2", launchType)
+	assert.Equal(t, "my-asg", capacityProvider)
+
+	launchType, _ = ecsLaunchType(nil, nil)
+	assert.Equal(t, "", launchType)
+}
+
+func TestEksServiceLaunchType(t *testing.T) {
+	nodes := map[string]*v1.Node{
+		"ip-10-0-1-23":        {ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{eksCapacityTypeLabel: "SPOT"}}},
+		"fargate-ip-10-0-2-7": {ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{eksComputeTypeLabel: "fargate"}}},
+	}
+	pod := func(app, nodeName string) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Labels: map[string]string{"app": app}},
+			Spec:       v1.PodSpec{NodeName: nodeName},
+		}
+	}
+	pods := []v1.Pod{pod("web", "ip-10-0-1-23"), pod("api", "fargate-ip-10-0-2-7"), pod("mixed", "ip-10-0-1-23"), pod("mixed", "fargate-ip-10-0-2-7")}
+	profiles := []*eks.FargateProfile{{Selectors: []*eks.FargateProfileSelector{
+		{Namespace: aws.String("default"), Labels: map[string]*string{"app": aws.String("batch")}},
+	}}}
+	selecting := func(app string) v1.Service {
+		return v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+			Spec:       v1.ServiceSpec{Selector: map[string]string{"app": app}},
+		}
+	}
+
+	for app, expected := range map[string][2]string{
+		"web":   {"ec2", "SPOT"},
+		"api":   {"fargate", "FARGATE"},
+		"mixed": {"mixed", ""},
+		"batch": {"fargate", "FARGATE"},
+		"idle":  {"ec2", ""},
+	} {
+		launchType, capacityProvider := eksServiceLaunchType(selecting(app), pods, nodes, profiles)
+		assert.Equal(t, expected, [2]string{launchType, capacityProvider}, app)
+	}
+}
+
+func TestEKSListServicesWithoutFargateProfiles(t *testing.T) {
+	apiLabels := map[string]string{"app": "api"}
+	clientset := k8sfake.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "fargate-ip-10-0-1-23", Labels: map[string]string{eksComputeTypeLabel: "fargate"}}},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "default", Labels: apiLabels},
+			Spec:       v1.PodSpec{NodeName: "fargate-ip-10-0-1-23"},
+		},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+			Spec:       v1.ServiceSpec{Selector: apiLabels},
+		},
+	)
+	fake := &fakeEKS{
+		profiles:     []*eks.FargateProfile{{FargateProfileName: aws.String("default")}},
+		describeErrs: map[string]error{"default": awserr.New("AccessDeniedException", "not authorized", nil)},
+	}
+	p := NewEKS(fake).WithClientset(func(*eks.Cluster) (kubernetes.Interface, error) {
+		return clientset, nil
+	})
+
+	// Services are placed by their pods and the profile is reported
+	services, err := p.ListServices(context.Background(), cluster.Cluster{Name: "prod", Scheduler: "eks"})
+	if assert.Len(t, services, 1) {
+		assert.Equal(t, "fargate", services[0].LaunchType)
+	}
+	if assert.IsType(t, Errors{}, err) && assert.Len(t, err.(Errors), 1) {
+		assert.Equal(t, "eks:DescribeFargateProfile", err.(Errors)[0].Operation)
+	}
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/upstream"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// eksListLimit is the number of objects requested per page of a Kubernetes
+// List call
+const eksListLimit = 500
+
+// NamespaceFilter limits Kubernetes discovery to some namespaces. When
+// Include is set only those namespaces are read; namespaces in Exclude are
+// never read.
+type NamespaceFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// DefaultNamespaceFilter is used by new EKS providers. It is read from the
+// comma-separated HARBORMASTER_NAMESPACES and
+// HARBORMASTER_EXCLUDE_NAMESPACES environment variables when set.
+var DefaultNamespaceFilter NamespaceFilter
+
+// ParseNamespaces splits a comma-separated list of namespaces
+func ParseNamespaces(s string) []string {
+	namespaces := []string{}
+	for _, namespace := range strings.Split(s, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// Allows reports whether a namespace passes the filter
+func (f NamespaceFilter) Allows(namespace string) bool {
+	for _, excluded := range f.Exclude {
+		if namespace == excluded {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, included := range f.Include {
+		if namespace == included {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldSelector excludes namespaces on the server, e.g.
+// "metadata.namespace!=kube-system"
+func (f NamespaceFilter) fieldSelector() string {
+	selectors := make([]string, len(f.Exclude))
+	for i, namespace := range f.Exclude {
+		selectors[i] = "metadata.namespace!=" + namespace
+	}
+	return strings.Join(selectors, ",")
+}
+
+// WithNamespaces limits discovery to the namespaces passing a filter
+func (p *EKS) WithNamespaces(f NamespaceFilter) *EKS {
+	p.namespaceFilter = f
+	return p
+}
+
+// listNamespaced runs a namespaced List call across the namespaces allowed by
+// the namespace filter: a single call for all namespaces, with excluded
+// namespaces left out by a field selector, or one call per included
+// namespace. list is called for every page with the namespace and options to
+// list with and returns the continue token of the page.
+func (p *EKS) listNamespaced(ctx context.Context, c cluster.Cluster, op string, list func(namespace string, opts metav1.ListOptions) (string, error)) error {
+	if len(p.namespaceFilter.Include) == 0 {
+		return listPages(ctx, c, op, metav1.ListOptions{FieldSelector: p.namespaceFilter.fieldSelector()}, func(opts metav1.ListOptions) (string, error) {
+			return list(metav1.NamespaceAll, opts)
+		})
+	}
+
+	for _, namespace := range p.namespaceFilter.Include {
+		if !p.namespaceFilter.Allows(namespace) {
+			continue
+		}
+		err := listPages(ctx, c, op, metav1.ListOptions{}, func(opts metav1.ListOptions) (string, error) {
+			return list(namespace, opts)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listPages pages through a List call eksListLimit objects at a time. Each
+// page is retried on its own, so a retry never repeats the pages before it.
+func listPages(ctx context.Context, c cluster.Cluster, op string, opts metav1.ListOptions, list func(opts metav1.ListOptions) (string, error)) error {
+	opts.Limit = eksListLimit
+	for {
+		var next string
+		err := upstream.Retry(ctx, op, func() (err error) {
+			next, err = list(opts)
+			return err
+		})
+		if err != nil {
+			return newError("eks", c.Name, op, err)
+		}
+		if next == "" {
+			return nil
+		}
+		opts.Continue = next
+	}
+}
+
+func init() {
+	if namespaces := os.Getenv("HARBORMASTER_NAMESPACES"); namespaces != "" {
+		DefaultNamespaceFilter.Include = ParseNamespaces(namespaces)
+	}
+	if namespaces := os.Getenv("HARBORMASTER_EXCLUDE_NAMESPACES"); namespaces != "" {
+		DefaultNamespaceFilter.Exclude = ParseNamespaces(namespaces)
+	}
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNamespaceFilter(t *testing.T) {
+	assert.Equal(t, []string{"web", "batch"}, ParseNamespaces(" web,,batch "))
+
+	all := NamespaceFilter{}
+	assert.True(t, all.Allows("default"))
+	assert.Equal(t, "", all.fieldSelector())
+
+	f := NamespaceFilter{Include: []string{"web", "kube-system"}, Exclude: []string{"kube-system"}}
+	assert.True(t, f.Allows("web"))
+	assert.False(t, f.Allows("kube-system"))
+	assert.False(t, f.Allows("default"))
+
+	f = NamespaceFilter{Exclude: []string{"kube-system", "kube-public"}}
+	assert.True(t, f.Allows("default"))
+	assert.Equal(t, "metadata.namespace!=kube-system,metadata.namespace!=kube-public", f.fieldSelector())
+}
+
+func TestEKSListPaginatesAcrossNamespaces(t *testing.T) {
+	pages := map[string]*v1.PodList{
+		"": {
+			ListMeta: metav1.ListMeta{Continue: "page-2"},
+			Items:    []v1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"}}},
+		},
+		"page-2": {
+			Items: []v1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "api"}}},
+		},
+	}
+	// The fake clientset doesn't record list options, so pages are served in
+	// the order they are asked for by following the continue tokens
+	token := ""
+	var lists []k8stesting.ListActionImpl
+	clientset := k8sfake.NewSimpleClientset()
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		lists = append(lists, action.(k8stesting.ListActionImpl))
+		page := pages[token]
+		token = page.Continue
+		return true, page, nil
+	})
+	p := NewEKS(&fakeEKS{}).WithClientset(func(*eks.Cluster) (kubernetes.Interface, error) {
+		return clientset, nil
+	}).WithNamespaces(NamespaceFilter{Exclude: []string{"kube-system"}})
+	c := cluster.Cluster{Name: "prod", Scheduler: "eks"}
+
+	eksPods, err := p.pods(context.Background(), clientset, c)
+	assert.Nil(t, err)
+	if assert.Len(t, eksPods, 2) {
+		assert.Equal(t, "web-1", eksPods[0].Name)
+		assert.Equal(t, "api-1", eksPods[1].Name)
+	}
+	if assert.Len(t, lists, 2) {
+		for _, list := range lists {
+			assert.Equal(t, metav1.NamespaceAll, list.GetNamespace())
+			assert.Equal(t, "metadata.namespace!=kube-system", list.GetListRestrictions().Fields.String())
+		}
+	}
+
+	// Included namespaces are listed one at a time
+	lists, token = nil, ""
+	pages[""].Continue = ""
+	p.WithNamespaces(NamespaceFilter{Include: []string{"default", "web"}})
+	_, err = p.pods(context.Background(), clientset, c)
+	assert.Nil(t, err)
+	if assert.Len(t, lists, 2) {
+		assert.Equal(t, "default", lists[0].GetNamespace())
+		assert.Equal(t, "web", lists[1].GetNamespace())
+	}
+}
@@ -0,0 +1,252 @@
+// Package provider discovers clusters, nodes, services, tasks and workloads
+// from the container schedulers supported by Harbormaster and normalizes them
+// into the cluster, node, service, task and workload models.
+package provider
+
+import (
+	"context"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/buzzsurfr/harbormaster/workload"
+)
+
+// Provider lists and describes the resources of a single scheduler
+type Provider interface {
+	// Scheduler returns the scheduler name used in normalized resources
+	Scheduler() string
+
+	ListClusters(ctx context.Context) ([]cluster.Cluster, error)
+	DescribeCluster(ctx context.Context, name string) (cluster.Cluster, error)
+	ListNodes(ctx context.Context, c cluster.Cluster) ([]node.Node, error)
+	DescribeNode(ctx context.Context, c cluster.Cluster, name string) (node.Node, error)
+	ListServices(ctx context.Context, c cluster.Cluster) ([]service.Service, error)
+	DescribeService(ctx context.Context, c cluster.Cluster, namespace, name string) (service.Detail, error)
+	ListTasks(ctx context.Context, c cluster.Cluster) ([]task.Task, error)
+	DescribeTask(ctx context.Context, c cluster.Cluster, id string) (task.Task, error)
+	ListWorkloads(ctx context.Context, c cluster.Cluster) ([]workload.Workload, error)
+}
+
+// Counter is implemented by providers that count the nodes, tasks and
+// services of their clusters themselves because the scheduler doesn't report
+// them. Counting reads every node, pod and service of a cluster, so it is
+// left out of ListClusters and DescribeCluster and only done where clusters
+// are shown.
+type Counter interface {
+	CountCluster(ctx context.Context, c cluster.Cluster) (cluster.Cluster, error)
+}
+
+// Set is an ordered collection of providers. Results are merged in the order
+// the providers appear in the set.
+type Set []Provider
+
+// Get returns the provider for a scheduler
+func (s Set) Get(scheduler string) (Provider, bool) {
+	for _, p := range s {
+		if p.Scheduler() == scheduler {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// ListClusters lists the clusters of every provider concurrently. A failing
+// provider is skipped and its error is returned along with the clusters of
+// the remaining providers.
+func (s Set) ListClusters(ctx context.Context) ([]cluster.Cluster, []*Error) {
+	targets, errs := s.targets(ctx)
+
+	clusters := make([]cluster.Cluster, len(targets))
+	for i, t := range targets {
+		clusters[i] = t.cluster
+	}
+	return clusters, errs
+}
+
+// CountClusters fills in the counts of clusters whose provider is a Counter,
+// fanning out across clusters. A cluster that can't be counted is returned
+// without counts and its error is returned.
+func (s Set) CountClusters(ctx context.Context, clusters []cluster.Cluster) ([]cluster.Cluster, []*Error) {
+	targets := make([]target, len(clusters))
+	for i, c := range clusters {
+		p, _ := s.Get(c.Scheduler)
+		targets[i] = target{provider: p, cluster: c}
+	}
+
+	counted := make([]cluster.Cluster, len(clusters))
+	copy(counted, clusters)
+	countErrs := forEach(ctx, len(targets), func(i int) error {
+		counter, ok := targets[i].provider.(Counter)
+		if !ok {
+			return nil
+		}
+		c, err := counter.CountCluster(ctx, targets[i].cluster)
+		if err != nil {
+			return err
+		}
+		counted[i] = c
+		return nil
+	})
+	return counted, errorsOf(targets, "CountCluster", countErrs)
+}
+
+// ListNodes lists the nodes of every cluster of every provider, fanning out
+// across clusters. A failing provider or cluster is skipped and its error is
+// returned along with the remaining nodes, which keep the order of their
+// providers and clusters.
+func (s Set) ListNodes(ctx context.Context) ([]node.Node, []*Error) {
+	clusters, errs := s.ListClusters(ctx)
+	nodes, clusterErrs := s.ListClusterNodes(ctx, clusters)
+	return nodes, append(errs, clusterErrs...)
+}
+
+// ListClusterNodes lists the nodes of clusters that were already listed,
+// fanning out across clusters
+func (s Set) ListClusterNodes(ctx context.Context, clusters []cluster.Cluster) ([]node.Node, []*Error) {
+	targets := s.targetsOf(clusters)
+
+	results := make([][]node.Node, len(targets))
+	clusterErrs := forEach(ctx, len(targets), func(i int) error {
+		var err error
+		results[i], err = targets[i].provider.ListNodes(ctx, targets[i].cluster)
+		return err
+	})
+
+	nodes := []node.Node{}
+	for _, result := range results {
+		nodes = append(nodes, result...)
+	}
+	return nodes, errorsOf(targets, "ListNodes", clusterErrs)
+}
+
+// ListServices lists the services of every cluster of every provider,
+// fanning out across clusters. A failing provider or cluster is skipped and
+// its error is returned along with the remaining services, which keep the
+// order of their providers and clusters.
+func (s Set) ListServices(ctx context.Context) ([]service.Service, []*Error) {
+	clusters, errs := s.ListClusters(ctx)
+	services, clusterErrs := s.ListClusterServices(ctx, clusters)
+	return services, append(errs, clusterErrs...)
+}
+
+// ListClusterServices lists the services of clusters that were already
+// listed, fanning out across clusters
+func (s Set) ListClusterServices(ctx context.Context, clusters []cluster.Cluster) ([]service.Service, []*Error) {
+	targets := s.targetsOf(clusters)
+
+	results := make([][]service.Service, len(targets))
+	clusterErrs := forEach(ctx, len(targets), func(i int) error {
+		var err error
+		results[i], err = targets[i].provider.ListServices(ctx, targets[i].cluster)
+		return err
+	})
+
+	services := []service.Service{}
+	for _, result := range results {
+		services = append(services, result...)
+	}
+	return services, errorsOf(targets, "ListServices", clusterErrs)
+}
+
+// ListTasks lists the tasks and pods of every cluster of every provider,
+// fanning out across clusters. A failing provider or cluster is skipped and
+// its error is returned along with the remaining tasks, which keep the order
+// of their providers and clusters.
+func (s Set) ListTasks(ctx context.Context) ([]task.Task, []*Error) {
+	clusters, errs := s.ListClusters(ctx)
+	tasks, clusterErrs := s.ListClusterTasks(ctx, clusters)
+	return tasks, append(errs, clusterErrs...)
+}
+
+// ListClusterTasks lists the tasks and pods of clusters that were already
+// listed, fanning out across clusters
+func (s Set) ListClusterTasks(ctx context.Context, clusters []cluster.Cluster) ([]task.Task, []*Error) {
+	targets := s.targetsOf(clusters)
+
+	results := make([][]task.Task, len(targets))
+	clusterErrs := forEach(ctx, len(targets), func(i int) error {
+		var err error
+		results[i], err = targets[i].provider.ListTasks(ctx, targets[i].cluster)
+		return err
+	})
+
+	tasks := []task.Task{}
+	for _, result := range results {
+		tasks = append(tasks, result...)
+	}
+	return tasks, errorsOf(targets, "ListTasks", clusterErrs)
+}
+
+// ListWorkloads lists the ECS services and Kubernetes workload controllers of
+// every cluster of every provider, fanning out across clusters. A failing
+// provider or cluster is skipped and its error is returned along with the
+// remaining workloads, which keep the order of their providers and clusters.
+func (s Set) ListWorkloads(ctx context.Context) ([]workload.Workload, []*Error) {
+	clusters, errs := s.ListClusters(ctx)
+	workloads, clusterErrs := s.ListClusterWorkloads(ctx, clusters)
+	return workloads, append(errs, clusterErrs...)
+}
+
+// ListClusterWorkloads lists the ECS services and Kubernetes workload
+// controllers of clusters that were already listed, fanning out across
+// clusters
+func (s Set) ListClusterWorkloads(ctx context.Context, clusters []cluster.Cluster) ([]workload.Workload, []*Error) {
+	targets := s.targetsOf(clusters)
+
+	results := make([][]workload.Workload, len(targets))
+	clusterErrs := forEach(ctx, len(targets), func(i int) error {
+		var err error
+		results[i], err = targets[i].provider.ListWorkloads(ctx, targets[i].cluster)
+		return err
+	})
+
+	workloads := []workload.Workload{}
+	for _, result := range results {
+		workloads = append(workloads, result...)
+	}
+	return workloads, errorsOf(targets, "ListWorkloads", clusterErrs)
+}
+
+// target is a cluster along with the provider that discovered it
+type target struct {
+	provider Provider
+	cluster  cluster.Cluster
+}
+
+// targets lists the clusters of every provider concurrently, in provider
+// order. Errors are returned along with the clusters of the remaining
+// providers and the clusters a failing provider could still describe.
+func (s Set) targets(ctx context.Context) ([]target, []*Error) {
+	results := make([][]cluster.Cluster, len(s))
+	providerErrs := forEach(ctx, len(s), func(i int) error {
+		var err error
+		results[i], err = s[i].ListClusters(ctx)
+		return err
+	})
+
+	errs := []*Error{}
+	targets := []target{}
+	for i, clusters := range results {
+		if providerErrs[i] != nil {
+			errs = append(errs, record(s[i].Scheduler(), "", "ListClusters", providerErrs[i])...)
+		}
+		for _, c := range clusters {
+			targets = append(targets, target{provider: s[i], cluster: c})
+		}
+	}
+	return targets, errs
+}
+
+// targetsOf pairs clusters that were already listed with the providers of
+// their schedulers
+func (s Set) targetsOf(clusters []cluster.Cluster) []target {
+	targets := make([]target, 0, len(clusters))
+	for _, c := range clusters {
+		if p, ok := s.Get(c.Scheduler); ok {
+			targets = append(targets, target{provider: p, cluster: c})
+		}
+	}
+	return targets
+}
@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/buzzsurfr/harbormaster/workload"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProvider serves fixed clusters, nodes, services, tasks and workloads
+type fakeProvider struct {
+	scheduler string
+	clusters  []cluster.Cluster
+	nodes     map[string][]node.Node
+	services  map[string][]service.Service
+	tasks     map[string][]task.Task
+	workloads map[string][]workload.Workload
+	err       error
+}
+
+func (p *fakeProvider) Scheduler() string {
+	return p.scheduler
+}
+
+func (p *fakeProvider) ListClusters(ctx context.Context) ([]cluster.Cluster, error) {
+	return p.clusters, p.err
+}
+
+func (p *fakeProvider) DescribeCluster(ctx context.Context, name string) (cluster.Cluster, error) {
+	for _, c := range p.clusters {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return cluster.Cluster{}, p.err
+}
+
+func (p *fakeProvider) ListNodes(ctx context.Context, c cluster.Cluster) ([]node.Node, error) {
+	return p.nodes[c.Name], nil
+}
+
+func (p *fakeProvider) DescribeNode(ctx context.Context, c cluster.Cluster, name string) (node.Node, error) {
+	for _, n := range p.nodes[c.Name] {
+		if n.Name == name {
+			return n, nil
+		}
+	}
+	return node.Node{}, p.err
+}
+
+func (p *fakeProvider) ListServices(ctx context.Context, c cluster.Cluster) ([]service.Service, error) {
+	return p.services[c.Name], nil
+}
+
+func (p *fakeProvider) DescribeService(ctx context.Context, c cluster.Cluster, namespace, name string) (service.Detail, error) {
+	for _, s := range p.services[c.Name] {
+		if s.Namespace == namespace && s.Name == name {
+			return service.Detail{Service: s}, nil
+		}
+	}
+	return service.Detail{}, p.err
+}
+
+func (p *fakeProvider) ListTasks(ctx context.Context, c cluster.Cluster) ([]task.Task, error) {
+	return p.tasks[c.Name], nil
+}
+
+func (p *fakeProvider) DescribeTask(ctx context.Context, c cluster.Cluster, id string) (task.Task, error) {
+	for _, t := range p.tasks[c.Name] {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return task.Task{}, p.err
+}
+
+func (p *fakeProvider) ListWorkloads(ctx context.Context, c cluster.Cluster) ([]workload.Workload, error) {
+	return p.workloads[c.Name], nil
+}
+
+func TestSetGet(t *testing.T) {
+	s := Set{&fakeProvider{scheduler: "ecs"}, &fakeProvider{scheduler: "eks"}}
+
+	p, ok := s.Get("eks")
+	assert.True(t, ok)
+	assert.Equal(t, "eks", p.Scheduler())
+
+	_, ok = s.Get("nomad")
+	assert.False(t, ok)
+}
+
+func TestSetListNodes(t *testing.T) {
+	ecsCluster := cluster.Cluster{Name: "default", Scheduler: "ecs"}
+	eksCluster := cluster.Cluster{Name: "prod", Scheduler: "eks"}
+	s := Set{
+		&fakeProvider{
+			scheduler: "ecs",
+			clusters:  []cluster.Cluster{ecsCluster},
+			nodes:     map[string][]node.Node{"default": {{Name: "i-1"}}},
+		},
+		&fakeProvider{
+			scheduler: "eks",
+			clusters:  []cluster.Cluster{eksCluster},
+			nodes:     map[string][]node.Node{"prod": {{Name: "n-1"}, {Name: "n-2"}}},
+		},
+	}
+
+	nodes, errs := s.ListNodes(context.Background())
+	assert.Empty(t, errs)
+	assert.Equal(t, []node.Node{{Name: "i-1"}, {Name: "n-1"}, {Name: "n-2"}}, nodes)
+}
+
+func TestSetListClustersSkipsFailingProvider(t *testing.T) {
+	failure := errors.New("AccessDeniedException")
+	s := Set{
+		&fakeProvider{scheduler: "ecs", err: failure},
+		&fakeProvider{scheduler: "eks", clusters: []cluster.Cluster{{Name: "prod", Scheduler: "eks"}}},
+	}
+
+	clusters, errs := s.ListClusters(context.Background())
+	assert.Equal(t, []cluster.Cluster{{Name: "prod", Scheduler: "eks"}}, clusters)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "ecs", errs[0].Scheduler)
+		assert.Equal(t, "ListClusters", errs[0].Operation)
+		assert.Equal(t, failure, errs[0].Err)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/buzzsurfr/harbormaster/api"
+	"github.com/buzzsurfr/harbormaster/provider"
+	"github.com/buzzsurfr/harbormaster/snapshot"
+	"github.com/buzzsurfr/harbormaster/upstream"
+)
+
+var router func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// HandleRequest is the Lambda function handler for every API route
+func HandleRequest(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Lambda Context
+	lc, _ := lambdacontext.FromContext(ctx)
+	log.Print(lc.ClientContext.Client.AppPackageName)
+
+	// Report the retries made for this event
+	ctx, retries := upstream.CountRetries(ctx)
+	defer retries.Emit()
+
+	return router(ctx, event)
+}
+
+func init() {
+	xray.Configure(xray.Config{
+		LogLevel: "info",
+	})
+
+	// Retry throttled and failed calls with backoff
+	sess := session.Must(session.NewSession(request.WithRetryer(aws.NewConfig(), upstream.NewAWSRetryer())))
+
+	// Initialize ECS
+	ecsSvc := ecs.New(sess)
+	xray.AWS(ecsSvc.Client)
+
+	// Initialize EKS
+	eksSvc := eks.New(sess)
+	xray.AWS(eksSvc.Client)
+
+	// Initialize EC2
+	ec2Svc := ec2.New(sess)
+	xray.AWS(ec2Svc.Client)
+
+	// Sessions and providers are shared by every route for the life of the
+	// execution environment
+	providers := provider.Set{provider.NewECS(ecsSvc).WithEC2(ec2Svc), provider.NewEKS(eksSvc).WithEC2(ec2Svc)}.Cached(provider.DefaultCacheTTL)
+
+	handler := api.New(providers)
+
+	// Serve the snapshots written by the Collector function, and their
+	// history, when configured
+	if bucket := os.Getenv("HARBORMASTER_SNAPSHOT_BUCKET"); bucket != "" {
+		s3Svc := s3.New(sess)
+		xray.AWS(s3Svc.Client)
+
+		store := snapshot.NewS3Store(s3Svc, bucket, os.Getenv("HARBORMASTER_SNAPSHOT_PREFIX"))
+		handler = api.New(snapshot.NewReader(store).Providers("ecs", "eks")).WithHistory(store)
+	}
+
+	router = api.LambdaRouter(handler.Routes())
+}
+
+func main() {
+	lambda.Start(HandleRequest)
+}
@@ -1,14 +1,101 @@
 package service
 
-import "github.com/buzzsurfr/harbormaster/cluster"
+import (
+	"time"
 
-// Node contains data for the normalized container instance/node
+	"github.com/buzzsurfr/harbormaster/cluster"
+)
+
+// Service contains data for the normalized ECS or Kubernetes service. For
+// Kubernetes services the counts and TaskDefinition, e.g. "Deployment/web:3",
+// come from the Deployments whose pods the service selects.
 type Service struct {
-	Name       string          `json:"name"`
-	Arn        string          `json:"arn"`
-	Status     string          `json:"status"`
-	Cluster    cluster.Cluster `json:"cluster"`
-	Scheduler  string          `json:"scheduler"`
-	LaunchType string          `json:"launchType"`
-	Namespace  string          `json:"namespace"`
+	Name             string            `json:"name"`
+	Arn              string            `json:"arn"`
+	Status           string            `json:"status"`
+	Cluster          cluster.Cluster   `json:"cluster"`
+	Scheduler        string            `json:"scheduler"`
+	LaunchType       string            `json:"launchType"`
+	CapacityProvider string            `json:"capacityProvider"`
+	Namespace        string            `json:"namespace"`
+	DesiredCount     int64             `json:"desiredCount"`
+	RunningCount     int64             `json:"runningCount"`
+	PendingCount     int64             `json:"pendingCount"`
+	TaskDefinition   string            `json:"taskDefinition"`
+	Type             string            `json:"type,omitempty"`
+	Selector         map[string]string `json:"selector,omitempty"`
+	ClusterIPs       []string          `json:"clusterIPs,omitempty"`
+	Ports            []Port            `json:"ports"`
+	LoadBalancers    []LoadBalancer    `json:"loadBalancers"`
+}
+
+// Port is a port exposed by a service. For Kubernetes services TargetPort is
+// the number or name of the container port traffic is sent to.
+type Port struct {
+	Name       string `json:"name,omitempty"`
+	Protocol   string `json:"protocol"`
+	Port       int64  `json:"port"`
+	TargetPort string `json:"targetPort"`
+	NodePort   int64  `json:"nodePort,omitempty"`
+}
+
+// LoadBalancer is an ECS load balancer or target group attached to a service,
+// or the ingress of a Kubernetes service of type LoadBalancer
+type LoadBalancer struct {
+	Name           string `json:"name,omitempty"`
+	TargetGroupArn string `json:"targetGroupArn,omitempty"`
+	ContainerName  string `json:"containerName,omitempty"`
+	ContainerPort  int64  `json:"containerPort,omitempty"`
+	Hostname       string `json:"hostname,omitempty"`
+	IP             string `json:"ip,omitempty"`
+}
+
+// Detail extends a service with the state of its deployments. For Kubernetes
+// services these come from the Deployment whose pods the service selects,
+// named by Controller.
+type Detail struct {
+	Service
+	Controller              string                  `json:"controller"`
+	DeploymentConfiguration DeploymentConfiguration `json:"deploymentConfiguration"`
+	Deployments             []Deployment            `json:"deployments"`
+	Conditions              []Condition             `json:"conditions"`
+}
+
+// DeploymentConfiguration is how new versions of a service are rolled out.
+// Strategy is the ECS deployment controller or the Kubernetes strategy type.
+type DeploymentConfiguration struct {
+	Strategy              string `json:"strategy"`
+	MaximumPercent        int64  `json:"maximumPercent"`
+	MinimumHealthyPercent int64  `json:"minimumHealthyPercent"`
+	MaxSurge              string `json:"maxSurge"`
+	MaxUnavailable        string `json:"maxUnavailable"`
+	CircuitBreaker        bool   `json:"circuitBreaker"`
+	Rollback              bool   `json:"rollback"`
+}
+
+// Deployment is an ECS deployment or a Kubernetes ReplicaSet. Status is
+// PRIMARY for the current deployment or ReplicaSet and ACTIVE or INACTIVE for
+// previous ones.
+type Deployment struct {
+	ID                 string     `json:"id"`
+	Status             string     `json:"status"`
+	TaskDefinition     string     `json:"taskDefinition"`
+	Revision           string     `json:"revision"`
+	DesiredCount       int64      `json:"desiredCount"`
+	RunningCount       int64      `json:"runningCount"`
+	PendingCount       int64      `json:"pendingCount"`
+	FailedCount        int64      `json:"failedCount"`
+	RolloutState       string     `json:"rolloutState"`
+	RolloutStateReason string     `json:"rolloutStateReason"`
+	CreatedAt          *time.Time `json:"createdAt"`
+	UpdatedAt          *time.Time `json:"updatedAt"`
+}
+
+// Condition is a Kubernetes Deployment condition
+type Condition struct {
+	Type           string     `json:"type"`
+	Status         string     `json:"status"`
+	Reason         string     `json:"reason"`
+	Message        string     `json:"message"`
+	LastUpdateTime *time.Time `json:"lastUpdateTime"`
 }
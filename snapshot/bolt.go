@@ -0,0 +1,150 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// snapshotsBucket holds every snapshot, keyed by its time
+var snapshotsBucket = []byte("snapshots")
+
+// BoltStore keeps snapshots in a local bbolt database, so the inventory can
+// be read as of any point in time within its retention
+type BoltStore struct {
+	db        *bolt.DB
+	retention time.Duration
+}
+
+// OpenBoltStore opens or creates the database at path
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(snapshotsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Close closes the database
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
+
+// WithRetention deletes snapshots once they are older than d, measured from
+// the time of the latest snapshot written. A retention of 0 keeps every
+// snapshot.
+func (b *BoltStore) WithRetention(d time.Duration) *BoltStore {
+	b.retention = d
+	return b
+}
+
+// Put writes a snapshot under its time and deletes the snapshots that have
+// passed the retention
+func (b *BoltStore) Put(ctx context.Context, s *Snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(snapshotsBucket)
+		if err := bucket.Put(key(s.Time), data); err != nil {
+			return err
+		}
+		if b.retention <= 0 {
+			return nil
+		}
+
+		// Keys are collected first, since deleting moves the cursor
+		var expired [][]byte
+		cutoff := key(s.Time.Add(-b.retention))
+		c := bucket.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.Next() {
+			expired = append(expired, k)
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Latest reads the snapshot with the latest time
+func (b *BoltStore) Latest(ctx context.Context) (*Snapshot, error) {
+	var s *Snapshot
+	err := b.db.View(func(tx *bolt.Tx) error {
+		_, v := tx.Bucket(snapshotsBucket).Cursor().Last()
+		return decode(v, &s)
+	})
+	return s, err
+}
+
+// At reads the latest snapshot taken at or before t
+func (b *BoltStore) At(ctx context.Context, t time.Time) (*Snapshot, error) {
+	var s *Snapshot
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(snapshotsBucket).Cursor()
+
+		// Seek finds the first snapshot at or after t
+		k, v := c.Seek(key(t))
+		switch {
+		case k == nil:
+			_, v = c.Last()
+		case !bytes.Equal(k, key(t)):
+			_, v = c.Prev()
+		}
+		return decode(v, &s)
+	})
+	return s, err
+}
+
+// Range reads the snapshots taken from one time to another, inclusive, in
+// the order they were taken. Only one snapshot is decoded at a time.
+func (b *BoltStore) Range(ctx context.Context, from, to time.Time, fn func(*Snapshot) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(snapshotsBucket).Cursor()
+		last := key(to)
+		for k, v := c.Seek(key(from)); k != nil && bytes.Compare(k, last) <= 0; k, v = c.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var s *Snapshot
+			if err := decode(v, &s); err != nil {
+				return err
+			}
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// key orders snapshots by time
+func key(t time.Time) []byte {
+	return []byte(t.UTC().Format(timeFormat))
+}
+
+// decode reads a stored snapshot, or returns ErrNoSnapshot when there is none
+func decode(data []byte, s **Snapshot) error {
+	if data == nil {
+		return ErrNoSnapshot
+	}
+	*s = &Snapshot{}
+	return json.Unmarshal(data, *s)
+}
@@ -0,0 +1,97 @@
+package snapshot
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoltStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenBoltStore(filepath.Join(dir, "harbormaster.db"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	_, err = store.Latest(ctx)
+	assert.Equal(t, ErrNoSnapshot, err)
+
+	first := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, store.Put(ctx, testSnapshot(first.Add(time.Duration(i)*5*time.Minute))))
+	}
+
+	latest, err := store.Latest(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Add(10*time.Minute), latest.Time)
+
+	// Exactly at, between and after snapshots
+	s, err := store.At(ctx, first.Add(5*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, first.Add(5*time.Minute), s.Time)
+	s, err = store.At(ctx, first.Add(7*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, first.Add(5*time.Minute), s.Time)
+	s, err = store.At(ctx, first.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, first.Add(10*time.Minute), s.Time)
+
+	_, err = store.At(ctx, first.Add(-time.Minute))
+	assert.Equal(t, ErrNoSnapshot, err)
+
+	var snapshots []*Snapshot
+	err = store.Range(ctx, first.Add(time.Minute), first.Add(10*time.Minute), func(s *Snapshot) error {
+		snapshots = append(snapshots, s)
+		return nil
+	})
+	assert.Nil(t, err)
+	if assert.Len(t, snapshots, 2) {
+		assert.Equal(t, first.Add(5*time.Minute), snapshots[0].Time)
+		assert.Equal(t, first.Add(10*time.Minute), snapshots[1].Time)
+	}
+}
+
+func TestBoltStoreRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenBoltStore(filepath.Join(dir, "harbormaster.db"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer store.Close()
+	store.WithRetention(10 * time.Minute)
+	ctx := context.Background()
+
+	first := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, store.Put(ctx, testSnapshot(first.Add(time.Duration(i)*5*time.Minute))))
+	}
+
+	// The snapshot at 12:00 is older than 10 minutes before the one at 12:15
+	_, err = store.At(ctx, first)
+	assert.Equal(t, ErrNoSnapshot, err)
+
+	var times []time.Time
+	err = store.Range(ctx, first, first.Add(time.Hour), func(s *Snapshot) error {
+		times = append(times, s.Time)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{first.Add(5 * time.Minute), first.Add(10 * time.Minute), first.Add(15 * time.Minute)}, times)
+}
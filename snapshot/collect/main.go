@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/buzzsurfr/harbormaster/provider"
+	"github.com/buzzsurfr/harbormaster/snapshot"
+	"github.com/buzzsurfr/harbormaster/upstream"
+)
+
+var collector *snapshot.Collector
+
+// HandleRequest collects a snapshot on every scheduled event
+func HandleRequest(ctx context.Context, event events.CloudWatchEvent) error {
+	// Report the retries made for this event
+	ctx, retries := upstream.CountRetries(ctx)
+	defer retries.Emit()
+
+	s, err := collector.Collect(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("collected %d clusters, %d nodes, %d services, %d tasks and %d workloads", len(s.Clusters), len(s.Nodes), len(s.Services), len(s.Tasks), len(s.Workloads))
+	return nil
+}
+
+func init() {
+	xray.Configure(xray.Config{
+		LogLevel: "info",
+	})
+
+	// Retry throttled and failed calls with backoff
+	sess := session.Must(session.NewSession(request.WithRetryer(aws.NewConfig(), upstream.NewAWSRetryer())))
+
+	// Initialize ECS
+	ecsSvc := ecs.New(sess)
+	xray.AWS(ecsSvc.Client)
+
+	// Initialize EKS
+	eksSvc := eks.New(sess)
+	xray.AWS(eksSvc.Client)
+
+	// Initialize EC2
+	ec2Svc := ec2.New(sess)
+	xray.AWS(ec2Svc.Client)
+
+	// Initialize S3
+	s3Svc := s3.New(sess)
+	xray.AWS(s3Svc.Client)
+
+	collector = &snapshot.Collector{
+		Providers: provider.Set{provider.NewECS(ecsSvc).WithEC2(ec2Svc), provider.NewEKS(eksSvc).WithEC2(ec2Svc)},
+		Store:     snapshot.NewS3Store(s3Svc, os.Getenv("HARBORMASTER_SNAPSHOT_BUCKET"), os.Getenv("HARBORMASTER_SNAPSHOT_PREFIX")),
+	}
+}
+
+func main() {
+	lambda.Start(HandleRequest)
+}
@@ -0,0 +1,137 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Diff is the change in inventory between two snapshots
+type Diff struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Clusters  KindDiff  `json:"clusters"`
+	Nodes     KindDiff  `json:"nodes"`
+	Services  KindDiff  `json:"services"`
+	Tasks     KindDiff  `json:"tasks"`
+	Workloads KindDiff  `json:"workloads"`
+}
+
+// KindDiff lists the resources of one kind that were added, removed or
+// changed, sorted by ID
+type KindDiff struct {
+	Added   []interface{} `json:"added"`
+	Removed []interface{} `json:"removed"`
+	Changed []Change      `json:"changed"`
+}
+
+// Change is a resource present in both snapshots whose fields changed
+type Change struct {
+	ID     string        `json:"id"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// FieldChange is the old and new value of a field. Nested fields are named
+// with their JSON path, e.g. "cluster.status".
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Compare returns the change in inventory from one snapshot to another
+func Compare(from, to *Snapshot) Diff {
+	return Diff{
+		From:      from.Time,
+		To:        to.Time,
+		Clusters:  compareKind(from, to, Clusters),
+		Nodes:     compareKind(from, to, Nodes),
+		Services:  compareKind(from, to, Services),
+		Tasks:     compareKind(from, to, Tasks),
+		Workloads: compareKind(from, to, Workloads),
+	}
+}
+
+func compareKind(from, to *Snapshot, kind string) KindDiff {
+	before, _ := from.Index(kind)
+	after, _ := to.Index(kind)
+	d := KindDiff{Added: []interface{}{}, Removed: []interface{}{}, Changed: []Change{}}
+
+	for _, id := range sortedIDs(after) {
+		old, ok := before[id]
+		if !ok {
+			d.Added = append(d.Added, after[id])
+			continue
+		}
+		if fields := compareFields(old, after[id]); len(fields) > 0 {
+			d.Changed = append(d.Changed, Change{ID: id, Fields: fields})
+		}
+	}
+	for _, id := range sortedIDs(before) {
+		if _, ok := after[id]; !ok {
+			d.Removed = append(d.Removed, before[id])
+		}
+	}
+
+	return d
+}
+
+// compareFields returns the fields that differ between two versions of a
+// resource, sorted by name
+func compareFields(old, new interface{}) []FieldChange {
+	before, after := fields(old), fields(new)
+
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+
+	changes := []FieldChange{}
+	for name := range names {
+		if !reflect.DeepEqual(before[name], after[name]) {
+			changes = append(changes, FieldChange{Field: name, From: before[name], To: after[name]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// fields flattens the JSON encoding of a resource into its leaf values keyed
+// by their JSON path
+func fields(v interface{}) map[string]interface{} {
+	var decoded interface{}
+	data, _ := json.Marshal(v)
+	json.Unmarshal(data, &decoded)
+
+	flat := map[string]interface{}{}
+	flatten("", decoded, flat)
+	return flat
+}
+
+func flatten(prefix string, v interface{}, flat map[string]interface{}) {
+	object, ok := v.(map[string]interface{})
+	if !ok {
+		flat[prefix] = v
+		return
+	}
+	for k, child := range object {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		flatten(k, child, flat)
+	}
+}
+
+// sortedIDs returns the keys of an index in order
+func sortedIDs(index map[string]interface{}) []string {
+	ids := make([]string, 0, len(index))
+	for id := range index {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,53 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	first := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	web := service.Service{Name: "web", Scheduler: "ecs", Status: "ACTIVE", Cluster: cluster.Cluster{Name: "default"}}
+	draining := web
+	draining.Status = "DRAINING"
+	api := service.Service{Name: "api", Scheduler: "ecs", Status: "ACTIVE", Cluster: cluster.Cluster{Name: "default"}}
+
+	from := &Snapshot{
+		Time:     first,
+		Clusters: []cluster.Cluster{prod},
+		Nodes:    []node.Node{{Name: "n-1", Scheduler: "eks", Status: "Ready", Cluster: prod}},
+		Services: []service.Service{web},
+	}
+	to := &Snapshot{
+		Time:     first.Add(time.Hour),
+		Clusters: []cluster.Cluster{prod},
+		Nodes:    []node.Node{{Name: "n-1", Scheduler: "eks", Status: "NotReady", Cluster: prod}},
+		Services: []service.Service{draining, api},
+	}
+
+	d := Compare(from, to)
+	assert.Equal(t, first, d.From)
+	assert.Equal(t, KindDiff{Added: []interface{}{}, Removed: []interface{}{}, Changed: []Change{}}, d.Clusters)
+	assert.Equal(t, []Change{{
+		ID:     "eks/prod/n-1",
+		Fields: []FieldChange{{Field: "status", From: "Ready", To: "NotReady"}},
+	}}, d.Nodes.Changed)
+	assert.Equal(t, []interface{}{api}, d.Services.Added)
+	assert.Empty(t, d.Services.Removed)
+	assert.Equal(t, []Change{{
+		ID:     "ecs/default/web",
+		Fields: []FieldChange{{Field: "status", From: "ACTIVE", To: "DRAINING"}},
+	}}, d.Services.Changed)
+}
+
+func TestCompareNestedFields(t *testing.T) {
+	before := node.Node{Name: "n-1", Cluster: cluster.Cluster{Name: "prod", Status: "ACTIVE"}}
+	after := node.Node{Name: "n-1", Cluster: cluster.Cluster{Name: "prod", Status: "UPDATING"}}
+
+	assert.Equal(t, []FieldChange{{Field: "Cluster.status", From: "ACTIVE", To: "UPDATING"}}, compareFields(before, after))
+}
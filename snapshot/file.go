@@ -0,0 +1,139 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// timeFormat names snapshots so they sort in the order they were taken
+const timeFormat = "20060102T150405.000000000Z"
+
+// FileStore keeps snapshots as JSON files in a local directory
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore returns a store that writes to dir, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+// Put writes a snapshot to a file named after its time. The file is renamed
+// into place so readers never see a partial snapshot.
+func (f *FileStore) Put(ctx context.Context, s *Snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(f.Dir, ".snapshot-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(f.Dir, s.Time.UTC().Format(timeFormat)+".json"))
+}
+
+// Latest reads the snapshot with the latest time
+func (f *FileStore) Latest(ctx context.Context) (*Snapshot, error) {
+	names, err := f.names()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, ErrNoSnapshot
+	}
+	return f.read(names[len(names)-1])
+}
+
+// At reads the latest snapshot taken at or before t
+func (f *FileStore) At(ctx context.Context, t time.Time) (*Snapshot, error) {
+	names, err := f.names()
+	if err != nil {
+		return nil, err
+	}
+
+	last := t.UTC().Format(timeFormat) + ".json"
+	for i := len(names) - 1; i >= 0; i-- {
+		if names[i] <= last {
+			return f.read(names[i])
+		}
+	}
+	return nil, ErrNoSnapshot
+}
+
+// Range reads the snapshots taken from one time to another, inclusive, one
+// at a time
+func (f *FileStore) Range(ctx context.Context, from, to time.Time, fn func(*Snapshot) error) error {
+	names, err := f.names()
+	if err != nil {
+		return err
+	}
+
+	first, last := from.UTC().Format(timeFormat)+".json", to.UTC().Format(timeFormat)+".json"
+	for _, name := range names {
+		if name < first || name > last {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s, err := f.read(name)
+		if err != nil {
+			return err
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// names lists the snapshot files in the order they were taken
+func (f *FileStore) names() ([]string, error) {
+	files, err := ioutil.ReadDir(f.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// ReadDir sorts by name, which sorts snapshots by time
+	var names []string
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".json") {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// read decodes a snapshot file
+func (f *FileStore) read(name string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(filepath.Join(f.Dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,120 @@
+package snapshot
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/buzzsurfr/harbormaster/workload"
+)
+
+// Kinds of resources kept in a snapshot
+const (
+	Clusters  = "clusters"
+	Nodes     = "nodes"
+	Services  = "services"
+	Tasks     = "tasks"
+	Workloads = "workloads"
+)
+
+// ClusterID identifies a cluster across snapshots as scheduler/name
+func ClusterID(c cluster.Cluster) string {
+	return c.Scheduler + "/" + c.Name
+}
+
+// NodeID identifies a node across snapshots as scheduler/cluster/name
+func NodeID(n node.Node) string {
+	return n.Scheduler + "/" + n.Cluster.Name + "/" + n.Name
+}
+
+// ServiceID identifies a service across snapshots as
+// scheduler/cluster/namespace/name, leaving out the namespace of ECS services
+func ServiceID(s service.Service) string {
+	parts := []string{s.Scheduler, s.Cluster.Name, s.Namespace, s.Name}
+	if s.Namespace == "" {
+		parts = []string{s.Scheduler, s.Cluster.Name, s.Name}
+	}
+	return strings.Join(parts, "/")
+}
+
+// TaskID identifies a task or pod across snapshots as scheduler/cluster/id
+func TaskID(t task.Task) string {
+	return t.Scheduler + "/" + t.Cluster.Name + "/" + t.ID
+}
+
+// WorkloadID identifies a workload across snapshots as
+// scheduler/cluster/namespace/kind/name, leaving out the namespace of ECS
+// services
+func WorkloadID(w workload.Workload) string {
+	parts := []string{w.Scheduler, w.Cluster.Name, w.Namespace, w.Kind, w.Name}
+	if w.Namespace == "" {
+		parts = []string{w.Scheduler, w.Cluster.Name, w.Kind, w.Name}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Index returns the resources of a kind keyed by their ID, and false for
+// unknown kinds
+func (s *Snapshot) Index(kind string) (map[string]interface{}, bool) {
+	index := map[string]interface{}{}
+	switch kind {
+	case Clusters:
+		for _, c := range s.Clusters {
+			index[ClusterID(c)] = c
+		}
+	case Nodes:
+		for _, n := range s.Nodes {
+			index[NodeID(n)] = n
+		}
+	case Services:
+		for _, svc := range s.Services {
+			index[ServiceID(svc)] = svc
+		}
+	case Tasks:
+		for _, t := range s.Tasks {
+			index[TaskID(t)] = t
+		}
+	case Workloads:
+		for _, w := range s.Workloads {
+			index[WorkloadID(w)] = w
+		}
+	default:
+		return nil, false
+	}
+	return index, true
+}
+
+// Revision is the state of a resource from the time of a snapshot until the
+// next revision. Item is nil while the resource doesn't exist.
+type Revision struct {
+	Time time.Time   `json:"time"`
+	Item interface{} `json:"item"`
+}
+
+// Revisions returns the states of a resource across snapshots, in the order
+// the snapshots were taken. A revision is recorded for the first snapshot
+// and for every snapshot in which the resource changed.
+func Revisions(snapshots []*Snapshot, kind, id string) []Revision {
+	revisions := []Revision{}
+	for _, s := range snapshots {
+		revisions = AppendRevision(revisions, s, kind, id)
+	}
+	return revisions
+}
+
+// AppendRevision appends the state of a resource in the next snapshot to
+// its revisions when it changed, so revisions can be built one snapshot at
+// a time
+func AppendRevision(revisions []Revision, s *Snapshot, kind, id string) []Revision {
+	index, _ := s.Index(kind)
+	item := index[id]
+
+	if n := len(revisions); n > 0 && reflect.DeepEqual(revisions[n-1].Item, item) {
+		return revisions
+	}
+	return append(revisions, Revision{Time: s.Time, Item: item})
+}
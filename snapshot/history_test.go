@@ -0,0 +1,29 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevisions(t *testing.T) {
+	first := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	ready := node.Node{Name: "n-1", Scheduler: "eks", Status: "Ready", Cluster: prod}
+	notReady := ready
+	notReady.Status = "NotReady"
+
+	snapshots := []*Snapshot{
+		{Time: first, Nodes: []node.Node{ready}},
+		{Time: first.Add(5 * time.Minute), Nodes: []node.Node{ready}},
+		{Time: first.Add(10 * time.Minute), Nodes: []node.Node{notReady}},
+		{Time: first.Add(15 * time.Minute)},
+	}
+
+	assert.Equal(t, []Revision{
+		{Time: first, Item: ready},
+		{Time: first.Add(10 * time.Minute), Item: notReady},
+		{Time: first.Add(15 * time.Minute), Item: nil},
+	}, Revisions(snapshots, Nodes, "eks/prod/n-1"))
+}
@@ -0,0 +1,266 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/provider"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/buzzsurfr/harbormaster/upstream"
+	"github.com/buzzsurfr/harbormaster/workload"
+)
+
+// DefaultReload is how long a Reader keeps the latest snapshot before
+// reading it from the store again
+const DefaultReload = 10 * time.Second
+
+// Reader serves the latest snapshot of a store
+type Reader struct {
+	store  Store
+	reload time.Duration
+
+	mu     sync.Mutex
+	latest *Snapshot
+	loaded time.Time
+}
+
+// NewReader returns a reader of the latest snapshot in store
+func NewReader(store Store) *Reader {
+	return &Reader{store: store, reload: DefaultReload}
+}
+
+// Latest returns the latest snapshot, reading it from the store at most once
+// every reload interval
+func (r *Reader) Latest(ctx context.Context) (*Snapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.latest != nil && time.Since(r.loaded) < r.reload {
+		return r.latest, nil
+	}
+
+	s, err := r.store.Latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.latest, r.loaded = s, time.Now()
+	return s, nil
+}
+
+// Providers returns a provider for each scheduler that answers from the
+// latest snapshot, so the API can serve snapshots in place of live discovery
+func (r *Reader) Providers(schedulers ...string) provider.Set {
+	return providers(r.Latest, schedulers)
+}
+
+// Providers returns a provider for each scheduler that answers from a single
+// snapshot
+func Providers(s *Snapshot, schedulers ...string) provider.Set {
+	return providers(func(ctx context.Context) (*Snapshot, error) {
+		return s, nil
+	}, schedulers)
+}
+
+func providers(load func(ctx context.Context) (*Snapshot, error), schedulers []string) provider.Set {
+	set := make(provider.Set, len(schedulers))
+	for i, scheduler := range schedulers {
+		set[i] = &snapshotProvider{load: load, scheduler: scheduler}
+	}
+	return set
+}
+
+// snapshotProvider answers for one scheduler from a snapshot
+type snapshotProvider struct {
+	load      func(ctx context.Context) (*Snapshot, error)
+	scheduler string
+}
+
+func (p *snapshotProvider) Scheduler() string {
+	return p.scheduler
+}
+
+// latest returns the snapshot and records its time as the time its results
+// were discovered
+func (p *snapshotProvider) latest(ctx context.Context) (*Snapshot, error) {
+	s, err := p.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	provider.Observe(ctx, s.Time)
+	return s, nil
+}
+
+// recorded returns every error recorded for the scheduler and, unless
+// clusterName is empty, for the cluster, or nil when there are none
+func (p *snapshotProvider) recorded(errs []*provider.Error, clusterName string) error {
+	var matching provider.Errors
+	for _, e := range errs {
+		if e.Scheduler == p.scheduler && (clusterName == "" || e.Cluster == clusterName) {
+			matching = append(matching, e)
+		}
+	}
+	if len(matching) == 0 {
+		return nil
+	}
+	return matching
+}
+
+// notFound reports a resource missing from the snapshot
+func (p *snapshotProvider) notFound(clusterName, format string, args ...interface{}) error {
+	return &provider.Error{
+		Scheduler: p.scheduler,
+		Cluster:   clusterName,
+		Operation: "snapshot:Read",
+		Kind:      upstream.NotFound,
+		Code:      provider.CodeNotFound,
+		Message:   fmt.Sprintf(format, args...),
+		Err:       provider.ErrNotFound,
+	}
+}
+
+func (p *snapshotProvider) ListClusters(ctx context.Context) ([]cluster.Cluster, error) {
+	s, err := p.latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := []cluster.Cluster{}
+	for _, c := range s.Clusters {
+		if c.Scheduler == p.scheduler {
+			clusters = append(clusters, c)
+		}
+	}
+	return clusters, p.recorded(s.ClusterErrors, "")
+}
+
+func (p *snapshotProvider) DescribeCluster(ctx context.Context, name string) (cluster.Cluster, error) {
+	s, err := p.latest(ctx)
+	if err != nil {
+		return cluster.Cluster{}, err
+	}
+
+	for _, c := range s.Clusters {
+		if c.Scheduler == p.scheduler && c.Name == name {
+			return c, nil
+		}
+	}
+	return cluster.Cluster{}, p.notFound(name, "cluster %s not found", name)
+}
+
+func (p *snapshotProvider) ListNodes(ctx context.Context, c cluster.Cluster) ([]node.Node, error) {
+	s, err := p.latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []node.Node{}
+	for _, n := range s.Nodes {
+		if n.Scheduler == p.scheduler && n.Cluster.Name == c.Name {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, p.recorded(s.NodeErrors, c.Name)
+}
+
+func (p *snapshotProvider) DescribeNode(ctx context.Context, c cluster.Cluster, name string) (node.Node, error) {
+	s, err := p.latest(ctx)
+	if err != nil {
+		return node.Node{}, err
+	}
+
+	for _, n := range s.Nodes {
+		if n.Scheduler == p.scheduler && n.Cluster.Name == c.Name && n.Matches(name) {
+			return n, nil
+		}
+	}
+	return node.Node{}, p.notFound(c.Name, "node %s not found", name)
+}
+
+func (p *snapshotProvider) ListServices(ctx context.Context, c cluster.Cluster) ([]service.Service, error) {
+	s, err := p.latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	services := []service.Service{}
+	for _, svc := range s.Services {
+		if svc.Scheduler == p.scheduler && svc.Cluster.Name == c.Name {
+			services = append(services, svc)
+		}
+	}
+	return services, p.recorded(s.ServiceErrors, c.Name)
+}
+
+// DescribeService fails with ErrNotStored for services in the snapshot.
+// Snapshots keep only the service itself, not the deployments, rollouts and
+// conditions of its detail.
+func (p *snapshotProvider) DescribeService(ctx context.Context, c cluster.Cluster, namespace, name string) (service.Detail, error) {
+	s, err := p.latest(ctx)
+	if err != nil {
+		return service.Detail{}, err
+	}
+
+	// ECS services have no namespace
+	for _, svc := range s.Services {
+		if svc.Scheduler == p.scheduler && svc.Cluster.Name == c.Name && svc.Name == name && (svc.Namespace == "" || svc.Namespace == namespace) {
+			return service.Detail{}, &provider.Error{
+				Scheduler: p.scheduler,
+				Cluster:   c.Name,
+				Operation: "snapshot:Read",
+				Kind:      upstream.InvalidInput,
+				Message:   fmt.Sprintf("the detail of service %s isn't stored in snapshots; list services instead", name),
+				Err:       ErrNotStored,
+			}
+		}
+	}
+	return service.Detail{}, p.notFound(c.Name, "service %s not found", name)
+}
+
+func (p *snapshotProvider) ListTasks(ctx context.Context, c cluster.Cluster) ([]task.Task, error) {
+	s, err := p.latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []task.Task{}
+	for _, t := range s.Tasks {
+		if t.Scheduler == p.scheduler && t.Cluster.Name == c.Name {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, p.recorded(s.TaskErrors, c.Name)
+}
+
+func (p *snapshotProvider) DescribeTask(ctx context.Context, c cluster.Cluster, id string) (task.Task, error) {
+	s, err := p.latest(ctx)
+	if err != nil {
+		return task.Task{}, err
+	}
+
+	for _, t := range s.Tasks {
+		if t.Scheduler == p.scheduler && t.Cluster.Name == c.Name && t.ID == id {
+			return t, nil
+		}
+	}
+	return task.Task{}, p.notFound(c.Name, "task %s not found", id)
+}
+
+func (p *snapshotProvider) ListWorkloads(ctx context.Context, c cluster.Cluster) ([]workload.Workload, error) {
+	s, err := p.latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workloads := []workload.Workload{}
+	for _, w := range s.Workloads {
+		if w.Scheduler == p.scheduler && w.Cluster.Name == c.Name {
+			workloads = append(workloads, w)
+		}
+	}
+	return workloads, p.recorded(s.WorkloadErrors, c.Name)
+}
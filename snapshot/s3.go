@@ -0,0 +1,160 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// latestKey is the key, below the prefix, of the copy of the latest snapshot.
+// It sorts after the keys of every snapshot.
+const latestKey = "latest.json"
+
+// s3AtWindow is how far before a point in time At looks for a snapshot
+// before listing every snapshot
+const s3AtWindow = 24 * time.Hour
+
+// S3Store keeps snapshots as JSON objects in an S3 bucket. Every snapshot is
+// written under its time and again as latest.json, so reading the latest
+// snapshot takes a single GetObject. Keys sort by time, so the history is
+// read by listing the keys between two times.
+type S3Store struct {
+	svc    s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Store returns a store that writes to a bucket below a key prefix, such
+// as "snapshots/"
+func NewS3Store(svc s3iface.S3API, bucket, prefix string) *S3Store {
+	return &S3Store{svc: svc, bucket: bucket, prefix: prefix}
+}
+
+// Put writes a snapshot under its time and as the latest snapshot
+func (st *S3Store) Put(ctx context.Context, s *Snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range []string{st.key(s.Time), st.prefix + latestKey} {
+		// s3:PutObject
+		_, err := st.svc.PutObjectWithContext(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(st.bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(data),
+			ContentType: aws.String("application/json"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Latest reads the latest snapshot
+func (st *S3Store) Latest(ctx context.Context) (*Snapshot, error) {
+	return st.read(ctx, st.prefix+latestKey)
+}
+
+// At reads the latest snapshot taken at or before t. Only the keys of the
+// day before t are listed, unless no snapshot was taken that day.
+func (st *S3Store) At(ctx context.Context, t time.Time) (*Snapshot, error) {
+	last := st.key(t)
+	for _, after := range []string{st.key(t.Add(-s3AtWindow)), ""} {
+		var found string
+		err := st.list(ctx, after, func(key string) bool {
+			if key > last {
+				return false
+			}
+			found = key
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if found != "" {
+			return st.read(ctx, found)
+		}
+	}
+	return nil, ErrNoSnapshot
+}
+
+// Range reads the snapshots taken from one time to another, inclusive, one
+// at a time
+func (st *S3Store) Range(ctx context.Context, from, to time.Time, fn func(*Snapshot) error) error {
+	last := st.key(to)
+	var rangeErr error
+	err := st.list(ctx, st.key(from.Add(-time.Nanosecond)), func(key string) bool {
+		if key > last {
+			return false
+		}
+		s, err := st.read(ctx, key)
+		if err == nil {
+			err = fn(s)
+		}
+		rangeErr = err
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return rangeErr
+}
+
+// key returns the key of the snapshot taken at t
+func (st *S3Store) key(t time.Time) string {
+	return st.prefix + t.UTC().Format(timeFormat) + ".json"
+}
+
+// list calls fn with the keys below the prefix that sort after a key, in
+// order, until fn returns false
+func (st *S3Store) list(ctx context.Context, after string, fn func(key string) bool) error {
+	input := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(st.bucket),
+		Prefix:    aws.String(st.prefix),
+		Delimiter: aws.String("/"),
+	}
+	if after != "" {
+		input.StartAfter = aws.String(after)
+	}
+
+	// s3:ListBucket
+	return st.svc.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			if !fn(aws.StringValue(object.Key)) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// read decodes the snapshot stored under a key
+func (st *S3Store) read(ctx context.Context, key string) (*Snapshot, error) {
+	// s3:GetObject
+	resultGetObject, err := st.svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(st.bucket),
+		Key:    aws.String(key),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrNoSnapshot
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resultGetObject.Body.Close()
+
+	s := &Snapshot{}
+	if err := json.NewDecoder(resultGetObject.Body).Decode(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,176 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is a local stand-in for the S3 PutObject, GetObject and
+// ListObjectsV2 calls, keyed by path-style /bucket/key paths. Lists return
+// pageSize keys at a time.
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	pageSize int
+}
+
+// listBucketResult is the body of a ListObjectsV2 response
+type listBucketResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct{ Key string }
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case "PUT":
+		data, _ := ioutil.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case "GET":
+		if query := r.URL.Query(); query.Get("list-type") == "2" {
+			f.list(w, strings.TrimSuffix(r.URL.Path, "/")+"/", query.Get("prefix"), query.Get("delimiter"), query.Get("start-after"), query.Get("continuation-token"))
+			return
+		}
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list writes the keys of a bucket that start with prefix, sort after
+// startAfter and the continuation token and don't contain the delimiter
+// after the prefix
+func (f *fakeS3) list(w http.ResponseWriter, bucket, prefix, delimiter, startAfter, token string) {
+	if token != "" {
+		startAfter = token
+	}
+
+	var keys []string
+	for path := range f.objects {
+		key := strings.TrimPrefix(path, bucket)
+		if !strings.HasPrefix(path, bucket) || !strings.HasPrefix(key, prefix) || key <= startAfter {
+			continue
+		}
+		if delimiter != "" && strings.Contains(strings.TrimPrefix(key, prefix), delimiter) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := listBucketResult{}
+	if len(keys) > f.pageSize {
+		keys = keys[:f.pageSize]
+		result.IsTruncated, result.NextContinuationToken = true, keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		result.Contents = append(result.Contents, struct{ Key string }{key})
+	}
+	xml.NewEncoder(w).Encode(result)
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, pageSize: 2}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(server.URL),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("AKID", "SECRET", ""),
+	}))
+	store := NewS3Store(s3.New(sess), "inventory", "snapshots/")
+
+	_, err := store.Latest(context.Background())
+	assert.Equal(t, ErrNoSnapshot, err)
+
+	taken := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	assert.Nil(t, store.Put(context.Background(), testSnapshot(taken)))
+	assert.Contains(t, fake.objects, "/inventory/snapshots/20181001T120000.000000000Z.json")
+
+	latest, err := store.Latest(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, testSnapshot(taken), latest)
+}
+
+func TestS3StoreHistory(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, pageSize: 2}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(server.URL),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("AKID", "SECRET", ""),
+	}))
+	store := NewS3Store(s3.New(sess), "inventory", "snapshots/")
+
+	first := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	_, err := store.At(context.Background(), first)
+	assert.Equal(t, ErrNoSnapshot, err)
+
+	// Five snapshots a minute apart, and one taken two days earlier
+	earlier := first.Add(-48 * time.Hour)
+	assert.Nil(t, store.Put(context.Background(), testSnapshot(earlier)))
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, store.Put(context.Background(), testSnapshot(first.Add(time.Duration(i)*time.Minute))))
+	}
+
+	s, err := store.At(context.Background(), first.Add(150*time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, first.Add(2*time.Minute), s.Time)
+
+	// Snapshots older than the window are still found
+	s, err = store.At(context.Background(), first.Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, earlier, s.Time)
+
+	_, err = store.At(context.Background(), earlier.Add(-time.Second))
+	assert.Equal(t, ErrNoSnapshot, err)
+
+	// Range is inclusive and doesn't read latest.json
+	var times []time.Time
+	err = store.Range(context.Background(), first.Add(time.Minute), first.Add(4*time.Minute), func(s *Snapshot) error {
+		times = append(times, s.Time)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{first.Add(time.Minute), first.Add(2 * time.Minute), first.Add(3 * time.Minute), first.Add(4 * time.Minute)}, times)
+
+	times = nil
+	err = store.Range(context.Background(), time.Time{}, first.Add(time.Hour), func(s *Snapshot) error {
+		times = append(times, s.Time)
+		if len(times) == 2 {
+			return ErrNoSnapshot
+		}
+		return nil
+	})
+	assert.Equal(t, ErrNoSnapshot, err)
+	assert.Equal(t, []time.Time{earlier, first}, times)
+}
@@ -0,0 +1,113 @@
+// Package snapshot separates slow discovery from request latency. A
+// Collector walks every cluster on a schedule and writes timestamped
+// snapshots of the inventory to a Store, and a Reader serves the latest
+// snapshot to the API in place of live providers.
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/provider"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/buzzsurfr/harbormaster/task"
+	"github.com/buzzsurfr/harbormaster/workload"
+)
+
+// ErrNoSnapshot is returned by stores that don't hold a snapshot yet
+var ErrNoSnapshot = errors.New("no snapshot has been collected")
+
+// ErrNotStored is returned by readers for details that snapshots don't keep
+var ErrNotStored = errors.New("not stored in snapshots")
+
+// Snapshot is the inventory of every scheduler at one point in time. The
+// errors of each kind of discovery are kept so readers can report the
+// clusters that couldn't be read.
+type Snapshot struct {
+	Time           time.Time           `json:"time"`
+	Clusters       []cluster.Cluster   `json:"clusters"`
+	Nodes          []node.Node         `json:"nodes"`
+	Services       []service.Service   `json:"services"`
+	Tasks          []task.Task         `json:"tasks"`
+	Workloads      []workload.Workload `json:"workloads"`
+	ClusterErrors  []*provider.Error   `json:"clusterErrors"`
+	NodeErrors     []*provider.Error   `json:"nodeErrors"`
+	ServiceErrors  []*provider.Error   `json:"serviceErrors"`
+	TaskErrors     []*provider.Error   `json:"taskErrors"`
+	WorkloadErrors []*provider.Error   `json:"workloadErrors"`
+}
+
+// Store keeps snapshots
+type Store interface {
+	// Put writes a snapshot
+	Put(ctx context.Context, s *Snapshot) error
+	// Latest returns the most recent snapshot, or ErrNoSnapshot
+	Latest(ctx context.Context) (*Snapshot, error)
+}
+
+// History is a store that keeps every snapshot, so the inventory can be read
+// as of any point in time
+type History interface {
+	Store
+	// At returns the latest snapshot taken at or before t, or ErrNoSnapshot
+	At(ctx context.Context, t time.Time) (*Snapshot, error)
+	// Range calls fn with the snapshots taken from one time to another,
+	// inclusive, one at a time in the order they were taken. It stops at
+	// the first error returned by fn.
+	Range(ctx context.Context, from, to time.Time, fn func(*Snapshot) error) error
+}
+
+// Take discovers the inventory of every provider. Clusters are listed once
+// and every other kind is discovered from that list.
+func Take(ctx context.Context, providers provider.Set) *Snapshot {
+	s := &Snapshot{Time: time.Now().UTC()}
+	clusters, clusterErrs := providers.ListClusters(ctx)
+	s.Clusters, s.ClusterErrors = providers.CountClusters(ctx, clusters)
+	s.ClusterErrors = append(clusterErrs, s.ClusterErrors...)
+	s.Nodes, s.NodeErrors = providers.ListClusterNodes(ctx, clusters)
+	s.Services, s.ServiceErrors = providers.ListClusterServices(ctx, clusters)
+	s.Tasks, s.TaskErrors = providers.ListClusterTasks(ctx, clusters)
+	s.Workloads, s.WorkloadErrors = providers.ListClusterWorkloads(ctx, clusters)
+	return s
+}
+
+// Collector writes a snapshot of its providers to a store on an interval
+type Collector struct {
+	Providers provider.Set
+	Store     Store
+	Interval  time.Duration
+}
+
+// Collect takes and stores a single snapshot
+func (c *Collector) Collect(ctx context.Context) (*Snapshot, error) {
+	s := Take(ctx, c.Providers)
+	if err := c.Store.Put(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run collects a snapshot immediately and then every interval until ctx is
+// done. Failed snapshots are logged and retried on the next interval.
+func (c *Collector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		if s, err := c.Collect(ctx); err != nil {
+			log.Printf("snapshot: %v", err)
+		} else {
+			log.Printf("snapshot: collected %d clusters, %d nodes, %d services, %d tasks and %d workloads", len(s.Clusters), len(s.Nodes), len(s.Services), len(s.Tasks), len(s.Workloads))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
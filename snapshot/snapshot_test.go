@@ -0,0 +1,158 @@
+package snapshot
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+	"github.com/buzzsurfr/harbormaster/node"
+	"github.com/buzzsurfr/harbormaster/provider"
+	"github.com/buzzsurfr/harbormaster/service"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	prod    = cluster.Cluster{Name: "prod", Scheduler: "eks", Status: "ACTIVE"}
+	staging = cluster.Cluster{Name: "staging", Scheduler: "eks", Status: "ACTIVE"}
+)
+
+func testSnapshot(t time.Time) *Snapshot {
+	return &Snapshot{
+		Time:     t,
+		Clusters: []cluster.Cluster{prod, staging},
+		Nodes:    []node.Node{{Name: "n-1", Scheduler: "eks", Cluster: prod}},
+		Services: []service.Service{{Name: "api", Namespace: "default", Scheduler: "eks", Cluster: prod}},
+		NodeErrors: []*provider.Error{
+			{Scheduler: "eks", Cluster: "staging", Operation: "kubernetes:ListNodes", Code: "Forbidden", Message: "nodes is forbidden"},
+		},
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshots")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(dir)
+	assert.Nil(t, err)
+
+	_, err = store.Latest(context.Background())
+	assert.Equal(t, ErrNoSnapshot, err)
+
+	older := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	newer := older.Add(5 * time.Minute)
+	assert.Nil(t, store.Put(context.Background(), testSnapshot(newer)))
+	assert.Nil(t, store.Put(context.Background(), testSnapshot(older)))
+
+	latest, err := store.Latest(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, testSnapshot(newer), latest)
+}
+
+// memoryStore keeps the latest snapshot in memory
+type memoryStore struct {
+	latest *Snapshot
+}
+
+func (m *memoryStore) Put(ctx context.Context, s *Snapshot) error {
+	m.latest = s
+	return nil
+}
+
+func (m *memoryStore) Latest(ctx context.Context) (*Snapshot, error) {
+	if m.latest == nil {
+		return nil, ErrNoSnapshot
+	}
+	return m.latest, nil
+}
+
+func TestReaderProviders(t *testing.T) {
+	discovered := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	providers := NewReader(&memoryStore{latest: testSnapshot(discovered)}).Providers("ecs", "eks")
+
+	ctx, freshness := provider.WithFreshness(context.Background())
+	nodes, errs := providers.ListNodes(ctx)
+	assert.Equal(t, []node.Node{{Name: "n-1", Scheduler: "eks", Cluster: prod}}, nodes)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "staging", errs[0].Cluster)
+		assert.Equal(t, "Forbidden", errs[0].Code)
+	}
+	assert.Equal(t, discovered, freshness.Discovered())
+
+	p, _ := providers.Get("eks")
+	_, err := p.DescribeNode(ctx, prod, "n-2")
+	assert.Equal(t, provider.CodeNotFound, err.(*provider.Error).Code)
+
+	// Service detail isn't kept in snapshots
+	_, err = p.DescribeService(ctx, prod, "default", "web")
+	assert.Equal(t, provider.CodeNotFound, err.(*provider.Error).Code)
+	_, err = p.DescribeService(ctx, prod, "default", "api")
+	assert.Equal(t, ErrNotStored, err.(*provider.Error).Err)
+}
+
+func TestReaderPartialResults(t *testing.T) {
+	s := testSnapshot(time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC))
+	s.Nodes = append(s.Nodes, node.Node{Name: "n-2", Scheduler: "eks", Cluster: staging})
+	s.ClusterErrors = []*provider.Error{
+		{Scheduler: "eks", Operation: "eks:ListClusters", Code: "ThrottlingException"},
+		{Scheduler: "eks", Cluster: "staging", Operation: "kubernetes:ListPods", Code: "Forbidden"},
+		{Scheduler: "ecs", Operation: "ecs:ListClusters", Code: "AccessDeniedException"},
+	}
+	s.NodeErrors = append(s.NodeErrors, &provider.Error{Scheduler: "eks", Cluster: "staging", Operation: "ec2:DescribeInstances", Code: "UnauthorizedOperation"})
+	p := Providers(s, "eks")[0]
+
+	// Every error of the scheduler is returned along with the clusters
+	clusters, err := p.ListClusters(context.Background())
+	assert.Equal(t, []cluster.Cluster{prod, staging}, clusters)
+	if errs, ok := err.(provider.Errors); assert.True(t, ok) {
+		assert.Equal(t, s.ClusterErrors[:2], []*provider.Error(errs))
+	}
+
+	// Nodes that were discovered are returned with the errors of the cluster
+	nodes, err := p.ListNodes(context.Background(), staging)
+	assert.Equal(t, []node.Node{{Name: "n-2", Scheduler: "eks", Cluster: staging}}, nodes)
+	if errs, ok := err.(provider.Errors); assert.True(t, ok) {
+		assert.Equal(t, s.NodeErrors, []*provider.Error(errs))
+	}
+
+	nodes, err = p.ListNodes(context.Background(), prod)
+	assert.Len(t, nodes, 1)
+	assert.Nil(t, err)
+}
+
+func TestReaderWithoutSnapshot(t *testing.T) {
+	clusters, errs := NewReader(&memoryStore{}).Providers("ecs").ListClusters(context.Background())
+	assert.Empty(t, clusters)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, ErrNoSnapshot, errs[0].Err)
+	}
+}
+
+// countingProvider counts the cluster lists of the provider it wraps
+type countingProvider struct {
+	provider.Provider
+	listClustersCalls int
+}
+
+func (p *countingProvider) ListClusters(ctx context.Context) ([]cluster.Cluster, error) {
+	p.listClustersCalls++
+	return p.Provider.ListClusters(ctx)
+}
+
+func TestTakeListsClustersOnce(t *testing.T) {
+	taken := testSnapshot(time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC))
+	counting := &countingProvider{Provider: Providers(taken, "eks")[0]}
+
+	s := Take(context.Background(), provider.Set{counting})
+	assert.Equal(t, 1, counting.listClustersCalls)
+	assert.Equal(t, taken.Clusters, s.Clusters)
+	assert.Equal(t, taken.Nodes, s.Nodes)
+	if assert.Len(t, s.NodeErrors, 1) {
+		assert.Equal(t, "staging", s.NodeErrors[0].Cluster)
+	}
+}
@@ -0,0 +1,56 @@
+package task
+
+import (
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+)
+
+// Task contains data for the normalized ECS task/Kubernetes pod
+type Task struct {
+	ID               string          `json:"id"`
+	Arn              string          `json:"arn"`
+	Name             string          `json:"name"`
+	Scheduler        string          `json:"scheduler"`
+	Cluster          cluster.Cluster `json:"cluster"`
+	Namespace        string          `json:"namespace"`
+	Node             string          `json:"node"`
+	Service          string          `json:"service"`
+	Owner            string          `json:"owner"`
+	DesiredStatus    string          `json:"desiredStatus"`
+	LastStatus       string          `json:"lastStatus"`
+	StartedAt        *time.Time      `json:"startedAt"`
+	LaunchType       string          `json:"launchType"`
+	CapacityProvider string          `json:"capacityProvider"`
+	Containers       []Container     `json:"containers"`
+}
+
+// Container contains data for a container of a task or pod
+type Container struct {
+	Name         string    `json:"name"`
+	Image        string    `json:"image"`
+	ImageDigest  string    `json:"imageDigest"`
+	Status       string    `json:"status"`
+	ExitCode     *int64    `json:"exitCode"`
+	RestartCount int64     `json:"restartCount"`
+	Health       string    `json:"health"`
+	Reservations Resources `json:"reservations"`
+	Limits       Resources `json:"limits"`
+}
+
+// Resources are the CPU, in millicores, and memory, in MiB, reserved for or
+// available to a container. Zero means none was set.
+type Resources struct {
+	CPU    int64 `json:"cpu"`
+	Memory int64 `json:"memory"`
+}
+
+// Match is a container found by a search, with the task or pod it runs in
+type Match struct {
+	Container
+	Task      string          `json:"task"`
+	Scheduler string          `json:"scheduler"`
+	Cluster   cluster.Cluster `json:"cluster"`
+	Namespace string          `json:"namespace"`
+	Node      string          `json:"node"`
+}
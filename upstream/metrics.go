@@ -0,0 +1,117 @@
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MetricNamespace is the CloudWatch namespace retry metrics are emitted in
+const MetricNamespace = "Harbormaster"
+
+// RetryCounts counts the retries made on behalf of a single invocation, such
+// as one Lambda event, by operation
+type RetryCounts struct {
+	mu      sync.Mutex
+	retries map[string]int64
+}
+
+// retryCountsKey carries the RetryCounts of an invocation in its context
+type retryCountsKey struct{}
+
+// CountRetries returns a context whose retries are counted in the returned
+// RetryCounts as well as in Retries
+func CountRetries(ctx context.Context) (context.Context, *RetryCounts) {
+	counts := &RetryCounts{retries: map[string]int64{}}
+	return context.WithValue(ctx, retryCountsKey{}, counts), counts
+}
+
+// countRetry counts a retry in Retries and in the RetryCounts of the context
+func countRetry(ctx context.Context, operation string) {
+	Retries.Add(operation, 1)
+	if counts, ok := ctx.Value(retryCountsKey{}).(*RetryCounts); ok {
+		counts.mu.Lock()
+		counts.retries[operation]++
+		counts.mu.Unlock()
+	}
+}
+
+// Get returns the number of retries of an operation
+func (c *RetryCounts) Get(operation string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.retries[operation]
+}
+
+// emfMetric names a metric of an embedded metric format document
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// emfDirective declares the metrics of an embedded metric format document
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+// emfMetadata is the "_aws" member of an embedded metric format document
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+// retryDocument reports the retries of one operation in CloudWatch embedded
+// metric format
+type retryDocument struct {
+	AWS       emfMetadata `json:"_aws"`
+	Operation string      `json:"Operation"`
+	Retries   int64       `json:"Retries"`
+}
+
+// WriteEMF writes a "Retries" metric with an "Operation" dimension for every
+// operation that was retried, one line each, in CloudWatch embedded metric
+// format. Lambda functions write it to standard output, where CloudWatch Logs
+// extracts the metrics.
+func (c *RetryCounts) WriteEMF(w io.Writer, now time.Time) error {
+	c.mu.Lock()
+	operations := make([]string, 0, len(c.retries))
+	for operation := range c.retries {
+		operations = append(operations, operation)
+	}
+	c.mu.Unlock()
+	sort.Strings(operations)
+
+	enc := json.NewEncoder(w)
+	for _, operation := range operations {
+		err := enc.Encode(retryDocument{
+			AWS: emfMetadata{
+				Timestamp: now.UnixNano() / int64(time.Millisecond),
+				CloudWatchMetrics: []emfDirective{{
+					Namespace:  MetricNamespace,
+					Dimensions: [][]string{{"Operation"}},
+					Metrics:    []emfMetric{{Name: "Retries", Unit: "Count"}},
+				}},
+			},
+			Operation: operation,
+			Retries:   c.Get(operation),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Emit writes the counts to standard output with WriteEMF
+func (c *RetryCounts) Emit() {
+	if err := c.WriteEMF(os.Stdout, time.Now()); err != nil {
+		log.Printf("retry metrics: %v", err)
+	}
+}
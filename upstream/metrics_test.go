@@ -0,0 +1,48 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountRetries(t *testing.T) {
+	ctx, counts := CountRetries(context.Background())
+	calls := 0
+	err := testPolicy.Do(ctx, "test:Counted", func() error {
+		calls++
+		if calls < 3 {
+			return awserr.New("ThrottlingException", "Rate exceeded", nil)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), counts.Get("test:Counted"))
+
+	// Other invocations are counted on their own
+	_, other := CountRetries(context.Background())
+	assert.Equal(t, int64(0), other.Get("test:Counted"))
+
+	var buf bytes.Buffer
+	assert.Nil(t, counts.WriteEMF(&buf, time.Unix(1538398800, 0)))
+	assert.JSONEq(t, `{
+		"_aws": {
+			"Timestamp": 1538398800000,
+			"CloudWatchMetrics": [{
+				"Namespace": "Harbormaster",
+				"Dimensions": [["Operation"]],
+				"Metrics": [{"Name": "Retries", "Unit": "Count"}]
+			}]
+		},
+		"Operation": "test:Counted",
+		"Retries": 2
+	}`, buf.String())
+
+	buf.Reset()
+	assert.Nil(t, other.WriteEMF(&buf, time.Now()))
+	assert.Equal(t, "", buf.String())
+}
@@ -0,0 +1,166 @@
+package upstream
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Retries counts the retried calls by operation, such as
+// "ecs:ListClusters" or "kubernetes:ListNodes". It is published by expvar as
+// "upstream_retries". Retries made on behalf of a single invocation are also
+// counted by CountRetries.
+var Retries = expvar.NewMap("upstream_retries")
+
+// Policy retries throttled and failed calls with jittered exponential
+// backoff. A delay requested by the server with Retry-After is always
+// honored, and no retry is made that would wait past the context deadline.
+type Policy struct {
+	// MaxAttempts is the number of calls made, including the first
+	MaxAttempts int
+	// BaseDelay is the upper bound of the first backoff. It doubles with
+	// every retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff, but not a delay requested with Retry-After
+	MaxDelay time.Duration
+}
+
+// DefaultPolicy is used by Retry and by the AWS clients of the Harbormaster
+// binaries
+var DefaultPolicy = Policy{
+	MaxAttempts: 5,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// Retry calls fn with DefaultPolicy
+func Retry(ctx context.Context, operation string, fn func() error) error {
+	return DefaultPolicy.Do(ctx, operation, fn)
+}
+
+// Do calls fn until it succeeds, fails with an error that isn't retryable or
+// runs out of attempts, and returns its last error
+func (p Policy) Do(ctx context.Context, operation string, fn func() error) error {
+	for retries := 0; ; retries++ {
+		err := fn()
+		if err == nil || retries+1 >= p.MaxAttempts || !Classify(err).Retryable() {
+			return err
+		}
+
+		retryAfter, _ := RetryAfter(err)
+		delay, ok := p.delay(ctx, retries, retryAfter)
+		if !ok {
+			return err
+		}
+
+		countRetry(ctx, operation)
+		log.Printf("%s: retrying in %s: %v", operation, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// delay returns how long to wait before retrying after the given number of
+// retries, and false when waiting would pass the context deadline
+func (p Policy) delay(ctx context.Context, retries int, retryAfter time.Duration) (time.Duration, bool) {
+	backoff := p.MaxDelay
+	if retries < 32 && p.BaseDelay<<uint(retries) < p.MaxDelay {
+		backoff = p.BaseDelay << uint(retries)
+	}
+
+	// Full jitter spreads out clients that were throttled together
+	var d time.Duration
+	if backoff > 0 {
+		d = time.Duration(rand.Int63n(int64(backoff) + 1))
+	}
+	if d < retryAfter {
+		d = retryAfter
+	}
+
+	if !fits(ctx, d) {
+		return 0, false
+	}
+	return d, true
+}
+
+// fits reports whether a wait of d ends before the context deadline
+func fits(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Now().Add(d).Before(deadline)
+}
+
+// RetryAfter returns the delay requested by a Kubernetes API server with
+// Retry-After
+func RetryAfter(err error) (time.Duration, bool) {
+	if seconds, ok := apierrors.SuggestsClientDelay(err); ok {
+		return time.Duration(seconds) * time.Second, true
+	}
+	return 0, false
+}
+
+// retryAfterHeader parses a Retry-After header given in seconds
+func retryAfterHeader(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// AWSRetryer applies a Policy to the AWS SDK's own retries
+type AWSRetryer struct {
+	Policy
+}
+
+// NewAWSRetryer returns a retryer for aws.Config that uses DefaultPolicy
+func NewAWSRetryer() request.Retryer {
+	return AWSRetryer{Policy: DefaultPolicy}
+}
+
+// MaxRetries returns the number of retries after the first call
+func (r AWSRetryer) MaxRetries() int {
+	return r.MaxAttempts - 1
+}
+
+// ShouldRetry retries throttled, timed out and server side failures, unless
+// a delay requested with Retry-After would pass the context deadline
+func (r AWSRetryer) ShouldRetry(req *request.Request) bool {
+	switch Classify(req.Error) {
+	case Throttled, Timeout:
+	case Failure:
+		if req.HTTPResponse == nil || req.HTTPResponse.StatusCode < 500 {
+			return request.IsErrorRetryable(req.Error)
+		}
+	default:
+		return false
+	}
+
+	return fits(req.Context(), retryAfterHeader(req.HTTPResponse))
+}
+
+// RetryRules returns the backoff before the next retry and counts it
+func (r AWSRetryer) RetryRules(req *request.Request) time.Duration {
+	d, ok := r.delay(req.Context(), req.RetryCount, retryAfterHeader(req.HTTPResponse))
+	if !ok {
+		// Let the SDK give up when the deadline passes during the wait
+		if deadline, ok := req.Context().Deadline(); ok {
+			d = time.Until(deadline)
+		}
+	}
+
+	countRetry(req.Context(), req.ClientInfo.ServiceName+":"+req.Operation.Name)
+	return d
+}
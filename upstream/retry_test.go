@@ -0,0 +1,87 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var testPolicy = Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+func TestDoRetriesThrottledCalls(t *testing.T) {
+	before := retryCount("test:Throttled")
+
+	calls := 0
+	err := testPolicy.Do(context.Background(), "test:Throttled", func() error {
+		calls++
+		if calls < 3 {
+			return awserr.New("ThrottlingException", "Rate exceeded", nil)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, before+2, retryCount("test:Throttled"))
+}
+
+func TestDoGivesUp(t *testing.T) {
+	calls := 0
+	err := testPolicy.Do(context.Background(), "test:Failure", func() error {
+		calls++
+		return errors.New("connection refused")
+	})
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "nodes"}, "n-1")
+	err = testPolicy.Do(context.Background(), "test:NotFound", func() error {
+		calls++
+		return notFound
+	})
+	assert.Equal(t, notFound, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDoHonorsRetryAfterAndDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The server asks for a longer wait than the deadline allows
+	calls := 0
+	start := time.Now()
+	err := testPolicy.Do(ctx, "test:RetryAfter", func() error {
+		calls++
+		return apierrors.NewTooManyRequests("slow down", 5)
+	})
+	assert.True(t, apierrors.IsTooManyRequests(err))
+	assert.Equal(t, 1, calls)
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+}
+
+func TestDelay(t *testing.T) {
+	for retries := 0; retries < 10; retries++ {
+		d, ok := testPolicy.delay(context.Background(), retries, 0)
+		assert.True(t, ok)
+		assert.True(t, d <= testPolicy.MaxDelay)
+	}
+
+	d, ok := testPolicy.delay(context.Background(), 0, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, d)
+}
+
+func retryCount(operation string) int64 {
+	v := Retries.Get(operation)
+	if v == nil {
+		return 0
+	}
+	return v.(*expvar.Int).Value()
+}
@@ -0,0 +1,144 @@
+// Package upstream classifies the errors returned by the AWS and Kubernetes
+// APIs that Harbormaster calls, so callers can decide how to report and
+// whether to retry a failure without knowing each service's error codes.
+package upstream
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Kind is the category of a failed call
+type Kind string
+
+// Categories of failed calls
+const (
+	// Throttled calls were rejected because of a request rate limit
+	Throttled Kind = "Throttled"
+	// NotFound calls named a resource that doesn't exist
+	NotFound Kind = "NotFound"
+	// AccessDenied calls were rejected because of missing or expired
+	// credentials or insufficient permissions
+	AccessDenied Kind = "AccessDenied"
+	// InvalidInput calls were rejected because of their parameters
+	InvalidInput Kind = "InvalidInput"
+	// Timeout calls didn't complete before their deadline
+	Timeout Kind = "Timeout"
+	// Canceled calls were abandoned by their caller
+	Canceled Kind = "Canceled"
+	// Failure covers every other error, including server errors and
+	// unreachable endpoints
+	Failure Kind = "Failure"
+)
+
+// Retryable reports whether a call that failed this way may succeed if it is
+// made again
+func (k Kind) Retryable() bool {
+	return k == Throttled || k == Timeout || k == Failure
+}
+
+// AWS error codes by category. Throttling codes are recognized by the SDK.
+var awsCodes = map[string]Kind{
+	// ECS, EKS
+	"ClusterNotFoundException":  NotFound,
+	"ServiceNotFoundException":  NotFound,
+	"ResourceNotFoundException": NotFound,
+	"NotFoundException":         NotFound,
+
+	// ECS, EKS, STS
+	"AccessDeniedException":       AccessDenied,
+	"AccessDenied":                AccessDenied,
+	"UnauthorizedOperation":       AccessDenied,
+	"UnrecognizedClientException": AccessDenied,
+	"InvalidClientTokenId":        AccessDenied,
+	"SignatureDoesNotMatch":       AccessDenied,
+	"MissingAuthenticationToken":  AccessDenied,
+	"ExpiredToken":                AccessDenied,
+	"ExpiredTokenException":       AccessDenied,
+	"RegionDisabledException":     AccessDenied,
+	"NoCredentialProviders":       AccessDenied,
+
+	// ECS, EKS, STS
+	"ClientException":             InvalidInput,
+	"InvalidParameterException":   InvalidInput,
+	"InvalidParameterValue":       InvalidInput,
+	"InvalidParameterCombination": InvalidInput,
+	"InvalidRequestException":     InvalidInput,
+	"MissingParameter":            InvalidInput,
+	"ValidationError":             InvalidInput,
+	"ValidationException":         InvalidInput,
+
+	"RequestTimeout":               Timeout,
+	"RequestTimeoutException":      Timeout,
+	request.ErrCodeResponseTimeout: Timeout,
+}
+
+// Classify returns the category of an error returned by an AWS or Kubernetes
+// API call
+func Classify(err error) Kind {
+	if err == nil {
+		return ""
+	}
+
+	switch err {
+	case context.DeadlineExceeded:
+		return Timeout
+	case context.Canceled:
+		return Canceled
+	}
+
+	if aerr, ok := err.(awserr.Error); ok {
+		if request.IsErrorThrottle(err) {
+			return Throttled
+		}
+		if kind, ok := awsCodes[aerr.Code()]; ok {
+			return kind
+		}
+		// Canceled requests and transport failures carry the original error
+		if orig := aerr.OrigErr(); orig != nil {
+			return Classify(orig)
+		}
+		if aerr.Code() == request.CanceledErrorCode {
+			return Canceled
+		}
+		return Failure
+	}
+
+	switch {
+	case apierrors.IsTooManyRequests(err):
+		return Throttled
+	case apierrors.IsNotFound(err):
+		return NotFound
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return AccessDenied
+	case apierrors.IsBadRequest(err), apierrors.IsInvalid(err):
+		return InvalidInput
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return Timeout
+	}
+
+	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		return Timeout
+	}
+
+	return Failure
+}
+
+// Code returns the service's own code and message for an error. Errors that
+// don't carry a code return an empty code and their text.
+func Code(err error) (code, message string) {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code(), aerr.Message()
+	}
+	return string(apierrors.ReasonForError(err)), err.Error()
+}
+
+// Log records a failed call along with where it was made and its category
+func Log(where, operation string, kind Kind, err error) {
+	log.Printf("%s: %s: %s: %v", where, operation, kind, err)
+}
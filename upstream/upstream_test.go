@@ -0,0 +1,58 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClassifyAWS(t *testing.T) {
+	tests := map[string]Kind{
+		"ThrottlingException":                  Throttled,
+		ecs.ErrCodeClusterNotFoundException:    NotFound,
+		eks.ErrCodeResourceNotFoundException:   NotFound,
+		ecs.ErrCodeAccessDeniedException:       AccessDenied,
+		"ExpiredTokenException":                AccessDenied,
+		ecs.ErrCodeClientException:             InvalidInput,
+		eks.ErrCodeInvalidParameterException:   InvalidInput,
+		ecs.ErrCodeServerException:             Failure,
+		eks.ErrCodeServiceUnavailableException: Failure,
+	}
+	for code, kind := range tests {
+		assert.Equal(t, kind, Classify(awserr.New(code, "message", nil)), code)
+	}
+
+	canceled := awserr.New(request.CanceledErrorCode, "request context canceled", context.DeadlineExceeded)
+	assert.Equal(t, Timeout, Classify(canceled))
+	canceled = awserr.New(request.CanceledErrorCode, "request context canceled", context.Canceled)
+	assert.Equal(t, Canceled, Classify(canceled))
+	assert.Equal(t, Canceled, Classify(awserr.New(request.CanceledErrorCode, "request context canceled", nil)))
+	assert.Equal(t, Canceled, Classify(context.Canceled))
+}
+
+func TestClassifyKubernetes(t *testing.T) {
+	nodes := schema.GroupResource{Resource: "nodes"}
+	assert.Equal(t, NotFound, Classify(apierrors.NewNotFound(nodes, "n-1")))
+	assert.Equal(t, AccessDenied, Classify(apierrors.NewForbidden(nodes, "", errors.New("denied"))))
+	assert.Equal(t, AccessDenied, Classify(apierrors.NewUnauthorized("expired token")))
+	assert.Equal(t, Throttled, Classify(apierrors.NewTooManyRequests("slow down", 1)))
+	assert.Equal(t, Timeout, Classify(apierrors.NewServerTimeout(nodes, "list", 1)))
+	assert.Equal(t, Failure, Classify(apierrors.NewInternalError(errors.New("etcd"))))
+	assert.Equal(t, Failure, Classify(errors.New("connection refused")))
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, Throttled.Retryable())
+	assert.True(t, Failure.Retryable())
+	assert.False(t, NotFound.Retryable())
+	assert.False(t, AccessDenied.Retryable())
+	assert.False(t, Canceled.Retryable())
+}
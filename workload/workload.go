@@ -0,0 +1,29 @@
+package workload
+
+import (
+	"time"
+
+	"github.com/buzzsurfr/harbormaster/cluster"
+)
+
+// Workload contains data for the normalized ECS service/Kubernetes workload
+// controller. Kind is Service for ECS services and Deployment, StatefulSet,
+// DaemonSet, Job or CronJob for Kubernetes controllers. Services names the
+// ECS service itself or the Kubernetes services that select the pods of the
+// controller.
+type Workload struct {
+	Name           string          `json:"name"`
+	Arn            string          `json:"arn"`
+	Kind           string          `json:"kind"`
+	Status         string          `json:"status"`
+	Cluster        cluster.Cluster `json:"cluster"`
+	Scheduler      string          `json:"scheduler"`
+	Namespace      string          `json:"namespace"`
+	DesiredCount   int64           `json:"desiredCount"`
+	RunningCount   int64           `json:"runningCount"`
+	PendingCount   int64           `json:"pendingCount"`
+	UpdatedCount   int64           `json:"updatedCount"`
+	TaskDefinition string          `json:"taskDefinition"`
+	Services       []string        `json:"services"`
+	CreatedAt      *time.Time      `json:"createdAt"`
+}